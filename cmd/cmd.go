@@ -1,11 +1,14 @@
 package cmd
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/alecthomas/kingpin/v2"
 	"github.com/pkg/errors"
@@ -13,66 +16,438 @@ import (
 
 	"github.com/pipego/cli/config"
 	"github.com/pipego/cli/dag"
+	"github.com/pipego/cli/manifest"
 	"github.com/pipego/cli/pipeline"
+	"github.com/pipego/cli/report"
 	"github.com/pipego/cli/runner"
 	"github.com/pipego/cli/scheduler"
-	_runner "github.com/pipego/dag/runner"
+	"github.com/pipego/cli/state"
 )
 
 var (
-	app           = kingpin.New("cli", "pipego cli").Version(config.Version + "-build-" + config.Build)
-	configFile    = app.Flag("config-file", "Config file (.yml)").Required().String()
-	runnerFile    = app.Flag("runner-file", "Runner file (.json)").Required().String()
-	schedulerFile = app.Flag("scheduler-file", "Scheduler file (.json)").Required().String()
+	app = kingpin.New("cli", "pipego cli").Version(config.Version + "-build-" + config.Build)
+
+	runCmd           = app.Command("run", "Run pipeline")
+	runManifestFile  = runCmd.Flag("manifest-file", "Manifest file of config, runner and scheduler (.yml)").String()
+	runConfigFile    = runCmd.Flag("config-file", "Config file (.yml)").String()
+	runRunnerFile    = runCmd.Flag("runner-file", "Runner file (.json|.yml)").String()
+	runSchedulerFile = runCmd.Flag("scheduler-file", "Scheduler file (.json|.yml)").String()
+	runOutput        = runCmd.Flag("output", "Output format (text|json|junit)").Default(report.FormatText).Enum(report.Formats...)
+	runLogDir        = runCmd.Flag("log-dir", "Directory to write log of each task (<task>.log)").String()
+	runDryRun        = runCmd.Flag("dry-run", "Print the execution plan without running tasks").Bool()
+	runTarget        = runCmd.Flag("target", "Run task and its depends (repeatable)").Strings()
+	runFrom          = runCmd.Flag("from", "Run task and its dependents (repeatable)").Strings()
+	runSkip          = runCmd.Flag("skip", "Skip task (repeatable)").Strings()
+	runTag           = runCmd.Flag("tag", "Run tasks of tag (repeatable)").Strings()
+	runStateDir      = runCmd.Flag("state-dir", "Directory to save state of runs (<name>/<run-id>.json)").Default(state.Dir).String()
+	runResume        = runCmd.Flag("resume", "Run ID to resume, running tasks not succeeded only").String()
+	runVarsFile      = runCmd.Flag("vars-file", "Vars file to render tasks with (.json|.yml)").String()
+	runSet           = runCmd.Flag("set", "Var to render tasks with, e.g. key=value (repeatable)").StringMap()
+
+	scheduleCmd           = app.Command("schedule", "Run scheduler")
+	scheduleManifestFile  = scheduleCmd.Flag("manifest-file", "Manifest file of config and scheduler (.yml)").String()
+	scheduleConfigFile    = scheduleCmd.Flag("config-file", "Config file (.yml)").String()
+	scheduleSchedulerFile = scheduleCmd.Flag("scheduler-file", "Scheduler file (.json|.yml)").String()
+	scheduleOutput        = scheduleCmd.Flag("output", "Output format (text|json|junit)").Default(report.FormatText).Enum(report.Formats...)
+
+	glanceCmd          = app.Command("glance", "Glance runner")
+	glanceManifestFile = glanceCmd.Flag("manifest-file", "Manifest file of config and runner (.yml)").String()
+	glanceConfigFile   = glanceCmd.Flag("config-file", "Config file (.yml)").String()
+	glanceRunnerFile   = glanceCmd.Flag("runner-file", "Runner file (.json|.yml), optional").String()
+	glanceOutput       = glanceCmd.Flag("output", "Output format (text|json|junit)").Default(report.FormatText).Enum(report.Formats...)
+	glanceDir          = glanceCmd.Flag("dir", "Directory to list, overriding spec.glance.dir.path").String()
+	glanceFile         = glanceCmd.Flag("file", "File to read, overriding spec.glance.file.path").String()
+	glanceMaxSize      = glanceCmd.Flag("max-size", "Max size of file to read, overriding spec.glance.file.maxSize").Int64()
+	glanceSys          = glanceCmd.Flag("sys", "Glance system, overriding spec.glance.sys.enable").IsSetByUser(&glanceSysSet).Bool()
+	glanceTimeout      = glanceCmd.Flag("timeout", "Timeout of glance, overriding spec.glance.timeout").String()
+	glanceSysSet       bool
+
+	maintCmd          = app.Command("maint", "Maint runner")
+	maintManifestFile = maintCmd.Flag("manifest-file", "Manifest file of config and runner (.yml)").String()
+	maintConfigFile   = maintCmd.Flag("config-file", "Config file (.yml)").String()
+	maintRunnerFile   = maintCmd.Flag("runner-file", "Runner file (.json|.yml), optional").String()
+	maintOutput       = maintCmd.Flag("output", "Output format (text|json|junit)").Default(report.FormatText).Enum(report.Formats...)
+	maintClockSync    = maintCmd.Flag("clock-sync", "Sync clock, overriding spec.maint.clock.sync").IsSetByUser(&maintClockSyncSet).Bool()
+	maintClockTime    = maintCmd.Flag("clock-time", "Time to check clock against, overriding spec.maint.clock.time").Int64()
+	maintTimeout      = maintCmd.Flag("timeout", "Timeout of maint, overriding spec.maint.timeout").String()
+	maintClockSyncSet bool
+
+	versionCmd          = app.Command("version", "Show version of cli (and runner if config and runner set)")
+	versionManifestFile = versionCmd.Flag("manifest-file", "Manifest file of config and runner (.yml)").String()
+	versionConfigFile   = versionCmd.Flag("config-file", "Config file (.yml)").String()
+	versionRunnerFile   = versionCmd.Flag("runner-file", "Runner file (.json|.yml)").String()
+	versionOutput       = versionCmd.Flag("output", "Output format (text|json|junit)").Default(report.FormatText).Enum(report.Formats...)
+
+	graphCmd          = app.Command("graph", "Export graph of pipeline")
+	graphManifestFile = graphCmd.Flag("manifest-file", "Manifest file of runner (.yml)").String()
+	graphRunnerFile   = graphCmd.Flag("runner-file", "Runner file (.json|.yml)").String()
+	graphReportFile   = graphCmd.Flag("report-file", "Report file of a previous run to color tasks by status (.json)").String()
+	graphTarget       = graphCmd.Flag("target", "Export task and its depends (repeatable)").Strings()
+	graphFrom         = graphCmd.Flag("from", "Export task and its dependents (repeatable)").Strings()
+	graphSkip         = graphCmd.Flag("skip", "Skip task (repeatable)").Strings()
+	graphTag          = graphCmd.Flag("tag", "Export tasks of tag (repeatable)").Strings()
+	graphOutput       = graphCmd.Flag("output", "Output format (dot|mermaid|json)").Default(dag.FormatDOT).Enum(dag.Formats...)
+	graphVarsFile     = graphCmd.Flag("vars-file", "Vars file to render tasks with (.json|.yml)").String()
+	graphSet          = graphCmd.Flag("set", "Var to render tasks with, e.g. key=value (repeatable)").StringMap()
+
+	validateCmd           = app.Command("validate", "Validate runner and scheduler")
+	validateManifestFile  = validateCmd.Flag("manifest-file", "Manifest file of config, runner and scheduler (.yml)").String()
+	validateRunnerFile    = validateCmd.Flag("runner-file", "Runner file (.json|.yml)").String()
+	validateSchedulerFile = validateCmd.Flag("scheduler-file", "Scheduler file (.json|.yml)").String()
+	validateVarsFile      = validateCmd.Flag("vars-file", "Vars file to render tasks with (.json|.yml)").String()
+	validateSet           = validateCmd.Flag("set", "Var to render tasks with, e.g. key=value (repeatable)").StringMap()
 )
 
 func Run(ctx context.Context) error {
-	kingpin.MustParse(app.Parse(os.Args[1:]))
+	switch kingpin.MustParse(app.Parse(os.Args[1:])) {
+	case runCmd.FullCommand():
+		return runCommand(ctx)
+	case scheduleCmd.FullCommand():
+		return scheduleCommand(ctx)
+	case glanceCmd.FullCommand():
+		return glanceCommand(ctx)
+	case maintCmd.FullCommand():
+		return maintCommand(ctx)
+	case versionCmd.FullCommand():
+		return versionCommand(ctx)
+	case graphCmd.FullCommand():
+		return graphCommand(ctx)
+	case validateCmd.FullCommand():
+		return validateCommand(ctx)
+	}
+
+	return nil
+}
 
-	cfg, err := initConfig(ctx, *configFile)
+func runCommand(ctx context.Context) (err error) {
+	rep := report.New("", app.Model().Version)
+
+	defer func() {
+		err = writeReport(*runOutput, rep, err)
+	}()
+
+	vars, err := loadVars(*runVarsFile, *runSet)
 	if err != nil {
-		return errors.Wrap(err, "failed to init config")
+		return errors.Wrap(err, "failed to load vars")
 	}
 
-	d, err := initDag(ctx, cfg)
+	m, err := initManifest(ctx, *runManifestFile, *runConfigFile, *runRunnerFile, *runSchedulerFile, vars, true)
 	if err != nil {
-		return errors.Wrap(err, "failed to init dag")
+		return errors.Wrap(err, "failed to init manifest")
+	}
+
+	if err := m.Require(manifest.KindConfig, manifest.KindRunner, manifest.KindScheduler); err != nil {
+		return errors.Wrap(err, "failed to init manifest")
 	}
 
-	t, g, m, c, err := initRunner(ctx, cfg, *runnerFile, d)
+	rep.Name = m.Config.MetaData.Name
+
+	d, err := initDag(ctx, m.Config, dag.Filter{Targets: *runTarget, From: *runFrom, Skip: *runSkip, Tags: *runTag})
 	if err != nil {
-		return errors.Wrap(err, "failed to init runner")
+		return errors.Wrap(err, "failed to init dag")
 	}
 
-	s, err := initScheduler(ctx, cfg, *schedulerFile)
+	s, err := initScheduler(ctx, m.Config, m.Scheduler)
 	if err != nil {
 		return errors.Wrap(err, "failed to init scheduler")
 	}
 
-	p, err := initPipeline(ctx, cfg, t, s)
+	pool, err := initPool(ctx, m.Config)
+	if err != nil {
+		return errors.Wrap(err, "failed to init pool")
+	}
+
+	defer func() {
+		_ = pool.Deinit(ctx)
+	}()
+
+	store := state.New(*runStateDir)
+
+	st, err := initState(store, m.Runner.Metadata.Name, *runResume)
+	if err != nil {
+		return errors.Wrap(err, "failed to init state")
+	}
+
+	rep.RunID = st.RunID
+
+	t, err := initTasker(ctx, m.Config, m.Runner, d, s, pool, st.Tasks)
+	if err != nil {
+		return errors.Wrap(err, "failed to init tasker")
+	}
+
+	p, err := initPipeline(ctx, m.Config, t, s)
 	if err != nil {
 		return errors.Wrap(err, "failed to init pipeline")
 	}
 
-	if err := runPipeline(ctx, t, p); err != nil {
+	if *runDryRun {
+		if err := runPlan(ctx, t, p, rep); err != nil {
+			return errors.Wrap(err, "failed to run plan")
+		}
+		return nil
+	}
+
+	if err := runState(ctx, t, p, rep, store, st); err != nil {
 		return errors.Wrap(err, "failed to run pipeline")
 	}
 
-	if err := runGlance(ctx, g); err != nil {
+	return nil
+}
+
+func scheduleCommand(ctx context.Context) (err error) {
+	rep := report.New("", app.Model().Version)
+
+	defer func() {
+		err = writeReport(*scheduleOutput, rep, err)
+	}()
+
+	m, err := initManifest(ctx, *scheduleManifestFile, *scheduleConfigFile, "", *scheduleSchedulerFile, nil, false)
+	if err != nil {
+		return errors.Wrap(err, "failed to init manifest")
+	}
+
+	if err := m.Require(manifest.KindConfig, manifest.KindScheduler); err != nil {
+		return errors.Wrap(err, "failed to init manifest")
+	}
+
+	rep.Name = m.Config.MetaData.Name
+
+	s, err := initScheduler(ctx, m.Config, m.Scheduler)
+	if err != nil {
+		return errors.Wrap(err, "failed to init scheduler")
+	}
+
+	if err := runSchedule(ctx, s, rep); err != nil {
+		return errors.Wrap(err, "failed to run schedule")
+	}
+
+	return nil
+}
+
+func glanceCommand(ctx context.Context) (err error) {
+	rep := report.New("", app.Model().Version)
+
+	defer func() {
+		err = writeReport(*glanceOutput, rep, err)
+	}()
+
+	m, err := initManifest(ctx, *glanceManifestFile, *glanceConfigFile, *glanceRunnerFile, "", nil, false)
+	if err != nil {
+		return errors.Wrap(err, "failed to init manifest")
+	}
+
+	if err := m.Require(manifest.KindConfig); err != nil {
+		return errors.Wrap(err, "failed to init manifest")
+	}
+
+	// Runner is optional, as the request is set by flags too.
+	if m.Runner == nil {
+		m.Runner = &runner.Proto{}
+	}
+
+	if err := setGlance(&m.Runner.Spec.Glance); err != nil {
+		return errors.Wrap(err, "invalid flags")
+	}
+
+	rep.Name = m.Config.MetaData.Name
+
+	pool, err := initPool(ctx, m.Config)
+	if err != nil {
+		return errors.Wrap(err, "failed to init pool")
+	}
+
+	defer func() {
+		_ = pool.Deinit(ctx)
+	}()
+
+	g, err := initGlancer(ctx, m.Config, m.Runner, pool)
+	if err != nil {
+		return errors.Wrap(err, "failed to init glancer")
+	}
+
+	if err := runGlance(ctx, g, rep); err != nil {
 		return errors.Wrap(err, "failed to run glance")
 	}
 
-	if err := runMaint(ctx, m); err != nil {
+	return nil
+}
+
+func maintCommand(ctx context.Context) (err error) {
+	rep := report.New("", app.Model().Version)
+
+	defer func() {
+		err = writeReport(*maintOutput, rep, err)
+	}()
+
+	m, err := initManifest(ctx, *maintManifestFile, *maintConfigFile, *maintRunnerFile, "", nil, false)
+	if err != nil {
+		return errors.Wrap(err, "failed to init manifest")
+	}
+
+	if err := m.Require(manifest.KindConfig); err != nil {
+		return errors.Wrap(err, "failed to init manifest")
+	}
+
+	// Runner is optional, as the request is set by flags too.
+	if m.Runner == nil {
+		m.Runner = &runner.Proto{}
+	}
+
+	if err := setMaint(&m.Runner.Spec.Maint); err != nil {
+		return errors.Wrap(err, "invalid flags")
+	}
+
+	rep.Name = m.Config.MetaData.Name
+
+	pool, err := initPool(ctx, m.Config)
+	if err != nil {
+		return errors.Wrap(err, "failed to init pool")
+	}
+
+	defer func() {
+		_ = pool.Deinit(ctx)
+	}()
+
+	mt, err := initMainter(ctx, m.Config, m.Runner, pool)
+	if err != nil {
+		return errors.Wrap(err, "failed to init mainter")
+	}
+
+	if err := runMaint(ctx, mt, rep); err != nil {
 		return errors.Wrap(err, "failed to run maint")
 	}
 
-	if err := runConfig(ctx, c); err != nil {
+	return nil
+}
+
+func versionCommand(ctx context.Context) (err error) {
+	rep := report.New("", app.Model().Version)
+
+	if *versionOutput == report.FormatText {
+		fmt.Println("    Run: cli")
+		fmt.Println("Version:", rep.Version)
+	}
+
+	defer func() {
+		err = writeReport(*versionOutput, rep, err)
+	}()
+
+	m, err := initManifest(ctx, *versionManifestFile, *versionConfigFile, *versionRunnerFile, "", nil, false)
+	if err != nil {
+		return errors.Wrap(err, "failed to init manifest")
+	}
+
+	if m.Require(manifest.KindConfig, manifest.KindRunner) != nil {
+		return nil
+	}
+
+	rep.Name = m.Config.MetaData.Name
+
+	pool, err := initPool(ctx, m.Config)
+	if err != nil {
+		return errors.Wrap(err, "failed to init pool")
+	}
+
+	defer func() {
+		_ = pool.Deinit(ctx)
+	}()
+
+	c, err := initConfiger(ctx, m.Config, m.Runner, pool)
+	if err != nil {
+		return errors.Wrap(err, "failed to init configer")
+	}
+
+	if err := runConfig(ctx, c, rep); err != nil {
 		return errors.Wrap(err, "failed to run config")
 	}
 
 	return nil
 }
 
+func graphCommand(ctx context.Context) error {
+	vars, err := loadVars(*graphVarsFile, *graphSet)
+	if err != nil {
+		return errors.Wrap(err, "failed to load vars")
+	}
+
+	m, err := initManifest(ctx, *graphManifestFile, "", *graphRunnerFile, "", vars, true)
+	if err != nil {
+		return errors.Wrap(err, "failed to init manifest")
+	}
+
+	if err := m.Require(manifest.KindRunner); err != nil {
+		return errors.Wrap(err, "failed to init manifest")
+	}
+
+	d, err := initGraph(ctx, m.Runner, dag.Filter{Targets: *graphTarget, From: *graphFrom, Skip: *graphSkip, Tags: *graphTag})
+	if err != nil {
+		return errors.Wrap(err, "failed to init graph")
+	}
+
+	status, err := loadStatus(*graphReportFile)
+	if err != nil {
+		return errors.Wrap(err, "failed to load report")
+	}
+
+	return dag.WriteGraph(os.Stdout, *graphOutput, d.Graph(ctx), status)
+}
+
+func validateCommand(ctx context.Context) error {
+	vars, err := loadVars(*validateVarsFile, *validateSet)
+	if err != nil {
+		return errors.Wrap(err, "failed to load vars")
+	}
+
+	m, dir, err := loadManifest(ctx, *validateManifestFile, "", *validateRunnerFile, *validateSchedulerFile)
+	if err != nil {
+		return errors.Wrap(err, "failed to load manifest")
+	}
+
+	if m.Runner == nil && m.Scheduler == nil {
+		return errors.New("runner or scheduler required")
+	}
+
+	errs := prepareManifest(m, vars, dir)
+
+	for _, item := range errs {
+		fmt.Println(item.Error())
+	}
+
+	if len(errs) != 0 {
+		return errors.Errorf("found %d problem(s)", len(errs))
+	}
+
+	fmt.Println("valid")
+
+	return nil
+}
+
+// validate joins errs of validation into one error.
+func validate(errs []error) error {
+	if len(errs) == 0 {
+		return nil
+	}
+
+	var buf []string
+
+	for _, item := range errs {
+		buf = append(buf, item.Error())
+	}
+
+	return errors.New("invalid: " + strings.Join(buf, "; "))
+}
+
+// writeReport writes rep in format to stdout, the error of the command wins over the one of writing.
+func writeReport(format string, rep *report.Report, err error) error {
+	rep.Finish(err)
+
+	if e := report.Write(os.Stdout, format, rep); e != nil && err == nil {
+		return errors.Wrap(e, "failed to write report")
+	}
+
+	return err
+}
+
 func initConfig(_ context.Context, name string) (*config.Config, error) {
 	c := config.New()
 
@@ -112,123 +487,389 @@ func loadFile(name string) ([]byte, error) {
 	return buf, nil
 }
 
-func initDag(ctx context.Context, cfg *config.Config) (dag.DAG, error) {
+func initDag(ctx context.Context, cfg *config.Config, filter dag.Filter) (dag.DAG, error) {
 	c := dag.DefaultConfig()
 	if c == nil {
 		return nil, errors.New("failed to config")
 	}
 
 	c.Config = *cfg
+	c.Filter = filter
 
 	return dag.New(ctx, c), nil
 }
 
-// nolint: funlen,gocyclo
-func initRunner(ctx context.Context, cfg *config.Config, name string, d dag.DAG) (runner.Tasker, runner.Glancer,
-	runner.Mainter, runner.Configer, error) {
-	tasker := func() (*runner.TaskerConfig, error) {
-		t := runner.TaskerDefaultConfig()
-		if t == nil {
-			return nil, errors.New("failed to config")
+func initPool(ctx context.Context, cfg *config.Config) (runner.Pool, error) {
+	c := runner.PoolDefaultConfig()
+	if c == nil {
+		return nil, errors.New("failed to config")
+	}
+
+	c.Config = *cfg
+
+	p := runner.PoolNew(ctx, c)
+	if err := p.Init(ctx); err != nil {
+		return nil, errors.Wrap(err, "failed to init")
+	}
+
+	return p, nil
+}
+
+// initGraph inits the dag of tasks for the graph only, hence no config is required.
+func initGraph(ctx context.Context, data *runner.Proto, filter dag.Filter) (dag.DAG, error) {
+	if err := validate(runner.Validate(data)); err != nil {
+		return nil, err
+	}
+
+	d, err := initDag(ctx, config.New(), filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var tasks []dag.Task
+
+	for i := range data.Spec.Tasks {
+		tasks = append(tasks, dag.Task{
+			Name:    data.Spec.Tasks[i].Name,
+			Depends: data.Spec.Tasks[i].Depends,
+			Tags:    data.Spec.Tasks[i].Tags,
+		})
+	}
+
+	if err := d.Init(ctx, tasks); err != nil {
+		return nil, errors.Wrap(err, "failed to init dag")
+	}
+
+	return d, nil
+}
+
+// loadStatus returns the status of tasks by name in the JSON report name, if set.
+func loadStatus(name string) (map[string]string, error) {
+	if name == "" {
+		return nil, nil
+	}
+
+	buf, err := loadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var r report.Report
+
+	if err := json.Unmarshal(buf, &r); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal")
+	}
+
+	status := map[string]string{}
+
+	for _, item := range r.Tasks {
+		status[item.Name] = item.Status
+	}
+
+	return status, nil
+}
+
+// initState loads the state of run id to resume if set, or else starts a new one of name.
+func initState(store *state.Store, name, runID string) (*state.State, error) {
+	if runID == "" {
+		return &state.State{Name: name, RunID: state.NewRunID()}, nil
+	}
+
+	st, err := store.Load(name, runID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load run "+runID)
+	}
+
+	return st, nil
+}
+
+// initManifest loads the manifest and then prepares it, failing with all the problems found.
+// The tasks of runner are dropped unless tasks is set, i.e. for the commands running them,
+// so that the other commands neither render them with vars nor check them.
+func initManifest(ctx context.Context, name, configFile, runnerFile, schedulerFile string,
+	vars map[string]string, tasks bool) (*manifest.Manifest, error) {
+	m, dir, err := loadManifest(ctx, name, configFile, runnerFile, schedulerFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load manifest")
+	}
+
+	if m.Runner != nil && !tasks {
+		m.Runner.Spec.Tasks = nil
+	}
+
+	if err := validate(prepareManifest(m, vars, dir)); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// loadManifest loads the manifest file if set, and then the config, runner and scheduler
+// files if set, which take precedence over the documents of the same kind in the manifest.
+// It returns the directory of the file of runner too.
+func loadManifest(ctx context.Context, name, configFile, runnerFile, schedulerFile string) (*manifest.Manifest, string, error) {
+	var err error
+
+	m := manifest.New()
+	dir := filepath.Dir(name)
+
+	if name != "" {
+		if m, err = manifest.Load(name); err != nil {
+			return nil, "", errors.Wrap(err, "failed to load manifest")
 		}
-		t.Config = *cfg
-		t.Dag = d
-		buf, err := loadFile(name)
-		if err != nil {
-			return nil, errors.Wrap(err, "failed to load")
+	}
+
+	if configFile != "" {
+		if m.Config, err = initConfig(ctx, configFile); err != nil {
+			return nil, "", errors.Wrap(err, "failed to load config")
 		}
-		if err := json.Unmarshal(buf, &t.Data); err != nil {
-			return nil, errors.Wrap(err, "failed to unmarshal")
+	}
+
+	if runnerFile != "" {
+		m.Runner = &runner.Proto{}
+		if err := loadProto(runnerFile, m.Runner); err != nil {
+			return nil, "", errors.Wrap(err, "failed to load runner")
 		}
-		return t, nil
+		dir = filepath.Dir(runnerFile)
 	}
 
-	glancer := func() (*runner.GlancerConfig, error) {
-		g := runner.GlancerDefaultConfig()
-		if g == nil {
-			return nil, errors.New("failed to config")
+	if schedulerFile != "" {
+		m.Scheduler = &scheduler.Proto{}
+		if err := loadProto(schedulerFile, m.Scheduler); err != nil {
+			return nil, "", errors.Wrap(err, "failed to load scheduler")
 		}
-		g.Config = *cfg
-		buf, err := loadFile(name)
-		if err != nil {
-			return nil, errors.Wrap(err, "failed to load")
+	}
+
+	return m, dir, nil
+}
+
+// prepareManifest validates the documents of m as written, so that every problem is reported with
+// the index in the file, and then resolves them: the tasks of runner are rendered with vars overriding
+// the vars of runner, their file.path is read relative to dir, and their matrix is expanded.
+func prepareManifest(m *manifest.Manifest, vars map[string]string, dir string) []error {
+	var errs []error
+
+	if m.Config != nil {
+		if err := config.Resolve(m.Config); err != nil {
+			errs = append(errs, errors.Wrap(err, manifest.KindConfig))
 		}
-		if err := json.Unmarshal(buf, &g.Data); err != nil {
-			return nil, errors.Wrap(err, "failed to unmarshal")
+	}
+
+	if m.Runner != nil {
+		for _, item := range prepareRunner(m.Runner, vars, dir) {
+			errs = append(errs, errors.Wrap(item, manifest.KindRunner))
 		}
-		return g, nil
 	}
 
-	mainter := func() (*runner.MainterConfig, error) {
-		m := runner.MainterDefaultConfig()
-		if m == nil {
-			return nil, errors.New("failed to config")
+	if m.Scheduler != nil {
+		for _, item := range scheduler.Validate(m.Scheduler) {
+			errs = append(errs, errors.Wrap(item, manifest.KindScheduler))
 		}
-		m.Config = *cfg
-		buf, err := loadFile(name)
+	}
+
+	return errs
+}
+
+func prepareRunner(data *runner.Proto, vars map[string]string, dir string) []error {
+	// Templates are rendered first, as any field may be a template.
+	if err := runner.Render(data, vars); err != nil {
+		return []error{err}
+	}
+
+	if errs := runner.Validate(data); len(errs) != 0 {
+		return errs
+	}
+
+	if err := runner.ReadFiles(data, dir); err != nil {
+		return []error{err}
+	}
+
+	if err := runner.Resolve(data); err != nil {
+		return []error{err}
+	}
+
+	if err := runner.Expand(data); err != nil {
+		return []error{err}
+	}
+
+	return nil
+}
+
+// setGlance overrides the request of glance by the flags set.
+func setGlance(g *runner.Glance) error {
+	if *glanceDir != "" {
+		g.Dir.Path = *glanceDir
+	}
+
+	if *glanceFile != "" {
+		g.File.Path = *glanceFile
+	}
+
+	if *glanceMaxSize != 0 {
+		g.File.MaxSize = *glanceMaxSize
+	}
+
+	if glanceSysSet {
+		g.Sys.Enable = *glanceSys
+	}
+
+	if *glanceTimeout != "" {
+		timeout, err := config.ParseTimeout(*glanceTimeout, runner.GlanceTimeout)
 		if err != nil {
-			return nil, errors.Wrap(err, "failed to load")
-		}
-		if err := json.Unmarshal(buf, &m.Data); err != nil {
-			return nil, errors.Wrap(err, "failed to unmarshal")
+			return errors.Wrap(err, "timeout")
 		}
-		return m, nil
+		g.Timeout = timeout.String()
+	}
+
+	return nil
+}
+
+// setMaint overrides the request of maint by the flags set.
+func setMaint(m *runner.Maint) error {
+	if maintClockSyncSet {
+		m.Clock.Sync = *maintClockSync
+	}
+
+	if *maintClockTime != 0 {
+		m.Clock.Time = *maintClockTime
 	}
 
-	configer := func() (*runner.ConfigerConfig, error) {
-		m := runner.ConfigerDefaultConfig()
-		if m == nil {
-			return nil, errors.New("failed to config")
+	if *maintTimeout != "" {
+		timeout, err := config.ParseTimeout(*maintTimeout, runner.MaintTimeout)
+		if err != nil {
+			return errors.Wrap(err, "timeout")
 		}
-		m.Config = *cfg
+		m.Timeout = timeout.String()
+	}
+
+	return nil
+}
+
+// loadVars returns the vars of file name if set, overridden by set.
+func loadVars(name string, set map[string]string) (map[string]string, error) {
+	vars := map[string]string{}
+
+	if name != "" {
 		buf, err := loadFile(name)
 		if err != nil {
 			return nil, errors.Wrap(err, "failed to load")
 		}
-		if err := json.Unmarshal(buf, &m.Data); err != nil {
+		// JSON is decoded as YAML too.
+		if err := yaml.Unmarshal(buf, &vars); err != nil {
 			return nil, errors.Wrap(err, "failed to unmarshal")
 		}
-		return m, nil
 	}
 
-	t, err := tasker()
-	if err != nil {
-		return nil, nil, nil, nil, errors.Wrap(err, "failed to init tasker")
+	for key, val := range set {
+		vars[key] = val
 	}
 
-	g, err := glancer()
+	return vars, nil
+}
+
+// loadProto decodes the runner or scheduler file of name into data, as JSON or YAML
+// detected by the file extension, or by the content for any other extension.
+func loadProto(name string, data interface{}) error {
+	buf, err := loadFile(name)
 	if err != nil {
-		return nil, nil, nil, nil, errors.Wrap(err, "failed to init glancer")
+		return errors.Wrap(err, "failed to load")
 	}
 
-	m, err := mainter()
-	if err != nil {
-		return nil, nil, nil, nil, errors.Wrap(err, "failed to init mainter")
+	if isYAML(name, buf) {
+		err = yaml.Unmarshal(buf, data)
+	} else {
+		err = json.Unmarshal(buf, data)
 	}
 
-	c, err := configer()
 	if err != nil {
-		return nil, nil, nil, nil, errors.Wrap(err, "failed to init configer")
+		return errors.Wrap(err, "failed to unmarshal")
 	}
 
-	return runner.TaskerNew(ctx, t), runner.GlancerNew(ctx, g), runner.MainterNew(ctx, m), runner.ConfigerNew(ctx, c), nil
+	return nil
 }
 
-func initScheduler(ctx context.Context, cfg *config.Config, name string) (scheduler.Scheduler, error) {
-	c := scheduler.DefaultConfig()
+func isYAML(name string, buf []byte) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".yml", ".yaml":
+		return true
+	case ".json":
+		return false
+	}
+
+	return !bytes.HasPrefix(bytes.TrimSpace(buf), []byte("{"))
+}
+
+func initTasker(ctx context.Context, cfg *config.Config, data *runner.Proto, d dag.DAG, s scheduler.Scheduler,
+	pool runner.Pool, resume []runner.TaskStatus) (runner.Tasker, error) {
+	c := runner.TaskerDefaultConfig()
 	if c == nil {
 		return nil, errors.New("failed to config")
 	}
 
 	c.Config = *cfg
+	c.Data = *data
+	c.Dag = d
+	c.Pool = pool
+	c.Resume = resume
+	c.Scheduler = s
+
+	if err := validate(runner.Validate(&c.Data)); err != nil {
+		return nil, err
+	}
 
-	buf, err := loadFile(name)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to load")
+	return runner.TaskerNew(ctx, c), nil
+}
+
+func initGlancer(ctx context.Context, cfg *config.Config, data *runner.Proto, pool runner.Pool) (runner.Glancer, error) {
+	c := runner.GlancerDefaultConfig()
+	if c == nil {
+		return nil, errors.New("failed to config")
 	}
 
-	if err := json.Unmarshal(buf, &c.Data); err != nil {
-		return nil, errors.Wrap(err, "failed to unmarshal")
+	c.Config = *cfg
+	c.Data = *data
+	c.Pool = pool
+
+	return runner.GlancerNew(ctx, c), nil
+}
+
+func initMainter(ctx context.Context, cfg *config.Config, data *runner.Proto, pool runner.Pool) (runner.Mainter, error) {
+	c := runner.MainterDefaultConfig()
+	if c == nil {
+		return nil, errors.New("failed to config")
+	}
+
+	c.Config = *cfg
+	c.Data = *data
+	c.Pool = pool
+
+	return runner.MainterNew(ctx, c), nil
+}
+
+func initConfiger(ctx context.Context, cfg *config.Config, data *runner.Proto, pool runner.Pool) (runner.Configer, error) {
+	c := runner.ConfigerDefaultConfig()
+	if c == nil {
+		return nil, errors.New("failed to config")
+	}
+
+	c.Config = *cfg
+	c.Data = *data
+	c.Pool = pool
+
+	return runner.ConfigerNew(ctx, c), nil
+}
+
+func initScheduler(ctx context.Context, cfg *config.Config, data *scheduler.Proto) (scheduler.Scheduler, error) {
+	c := scheduler.DefaultConfig()
+	if c == nil {
+		return nil, errors.New("failed to config")
+	}
+
+	c.Config = *cfg
+	c.Data = *data
+
+	if err := validate(scheduler.Validate(&c.Data)); err != nil {
+		return nil, err
 	}
 
 	return scheduler.New(ctx, c), nil
@@ -247,125 +888,169 @@ func initPipeline(ctx context.Context, cfg *config.Config, tasker runner.Tasker,
 	return pipeline.New(ctx, c), nil
 }
 
-// nolint: gosec
-func runPipeline(ctx context.Context, tasker runner.Tasker, pipe pipeline.Pipeline) error {
+func runPipeline(ctx context.Context, tasker runner.Tasker, pipe pipeline.Pipeline, rep *report.Report, live bool) error {
+	var out io.Writer
+
+	if live {
+		out = os.Stdout
+	}
+
+	pr, err := newPrinter(out, *runLogDir)
+	if err != nil {
+		return errors.Wrap(err, "failed to init printer")
+	}
+
 	if err := pipe.Init(ctx); err != nil {
 		return errors.Wrap(err, "failed to init")
 	}
 
-	s, l, err := pipe.Run(ctx)
+	if live {
+		fmt.Println("    Run: runner.tasker")
+	}
+
+	done := make(chan error, 1)
+	go pr.Run(ctx, pipe.Tail(ctx), done)
+
+	err = pipe.Run(ctx)
+
+	_ = pipe.Deinit(ctx)
+	e := <-done
+
+	rep.Tasks = tasker.Status(ctx)
+
 	if err != nil {
 		return errors.Wrap(err, "failed to run")
 	}
 
-	fmt.Println("    Run: scheduler")
-	fmt.Println("   Name:", s.Name)
-	fmt.Println("  Error:", s.Error)
+	if e != nil {
+		return errors.Wrap(e, "failed to print")
+	}
 
-	fmt.Println()
-	fmt.Println("    Run: runner.tasker")
+	return nil
+}
 
-	done := make(chan bool, 1)
-	go printer(ctx, tasker, l, done)
+// runState runs the pipeline and then merges the status of tasks run into st saved, which
+// is left as is if the tasker has not run, e.g. failed to init.
+func runState(ctx context.Context, tasker runner.Tasker, pipe pipeline.Pipeline, rep *report.Report,
+	store *state.Store, st *state.State) error {
+	err := runPipeline(ctx, tasker, pipe, rep, *runOutput == report.FormatText)
 
-L:
-	for {
-		select {
-		case <-done:
-			break L
-		case <-ctx.Done():
-			break L
-		}
+	if rep.Tasks == nil {
+		return err
 	}
 
-	_ = pipe.Deinit(ctx)
+	st.Merge(rep.Tasks)
 
-	return nil
+	if e := store.Save(st); e != nil && err == nil {
+		return errors.Wrap(e, "failed to save state")
+	}
+
+	return err
 }
 
-func printer(ctx context.Context, tasker runner.Tasker, log _runner.Log, done chan<- bool) {
-	tasks := tasker.Tasks(ctx)
+func runPlan(ctx context.Context, tasker runner.Tasker, pipe pipeline.Pipeline, rep *report.Report) error {
+	if err := pipe.Init(ctx); err != nil {
+		return errors.Wrap(err, "failed to init")
+	}
+
+	defer func() {
+		_ = pipe.Deinit(ctx)
+	}()
+
+	plan, err := tasker.Plan(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to plan")
+	}
+
+	rep.Plan = plan
 
-	for range tasks {
-		for {
-			line := <-log.Line
-			fmt.Println("    Pos:", line.Pos)
-			fmt.Println("   Time:", line.Time)
-			fmt.Println("Message:", line.Message)
-			if line.Message == "EOF" {
-				break
-			}
+	var failed []string
+
+	for i := range plan {
+		if plan[i].Error != "" {
+			failed = append(failed, plan[i].Name)
 		}
 	}
 
-	done <- true
+	if len(failed) != 0 {
+		return errors.New("failed to schedule tasks: " + strings.Join(failed, ", "))
+	}
+
+	return nil
 }
 
-func runGlance(ctx context.Context, glancer runner.Glancer) error {
-	if err := glancer.Init(ctx); err != nil {
+func runSchedule(ctx context.Context, sched scheduler.Scheduler, rep *report.Report) error {
+	if err := sched.Init(ctx); err != nil {
 		return errors.Wrap(err, "failed to init")
 	}
 
-	out, err := glancer.Run(ctx)
+	defer func() {
+		_ = sched.Deinit(ctx)
+	}()
+
+	s, err := sched.Run(ctx, sched.Task(ctx))
 	if err != nil {
 		return errors.Wrap(err, "failed to run")
 	}
 
-	buf, err := json.MarshalIndent(out, "", "  ")
-	if err != nil {
-		return errors.Wrap(err, "failed to marshal")
+	rep.Schedule = &s
+
+	return nil
+}
+
+func runGlance(ctx context.Context, glancer runner.Glancer, rep *report.Report) error {
+	if err := glancer.Init(ctx); err != nil {
+		return errors.Wrap(err, "failed to init")
 	}
 
-	fmt.Println("    Run: runner.glancer")
-	fmt.Println(" Output:", string(buf))
+	defer func() {
+		_ = glancer.Deinit(ctx)
+	}()
 
-	_ = glancer.Deinit(ctx)
+	out, err := glancer.Run(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to run")
+	}
+
+	rep.Glance = &out
 
 	return nil
 }
 
-func runMaint(ctx context.Context, mainter runner.Mainter) error {
+func runMaint(ctx context.Context, mainter runner.Mainter, rep *report.Report) error {
 	if err := mainter.Init(ctx); err != nil {
 		return errors.Wrap(err, "failed to init")
 	}
 
+	defer func() {
+		_ = mainter.Deinit(ctx)
+	}()
+
 	out, err := mainter.Run(ctx)
 	if err != nil {
 		return errors.Wrap(err, "failed to run")
 	}
 
-	buf, err := json.MarshalIndent(out, "", "  ")
-	if err != nil {
-		return errors.Wrap(err, "failed to marshal")
-	}
-
-	fmt.Println("    Run: runner.mainter")
-	fmt.Println(" Output:", string(buf))
-
-	_ = mainter.Deinit(ctx)
+	rep.Maint = &out
 
 	return nil
 }
 
-func runConfig(ctx context.Context, configer runner.Configer) error {
+func runConfig(ctx context.Context, configer runner.Configer, rep *report.Report) error {
 	if err := configer.Init(ctx); err != nil {
 		return errors.Wrap(err, "failed to init")
 	}
 
+	defer func() {
+		_ = configer.Deinit(ctx)
+	}()
+
 	out, err := configer.Run(ctx)
 	if err != nil {
 		return errors.Wrap(err, "failed to run")
 	}
 
-	buf, err := json.MarshalIndent(out, "", "  ")
-	if err != nil {
-		return errors.Wrap(err, "failed to marshal")
-	}
-
-	fmt.Println("    Run: runner.configer")
-	fmt.Println(" Output:", string(buf))
-
-	_ = configer.Deinit(ctx)
+	rep.Config = &out
 
 	return nil
 }
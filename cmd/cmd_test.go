@@ -3,11 +3,21 @@ package cmd
 import (
 	"context"
 	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 
+	"github.com/pipego/cli/dag"
+	"github.com/pipego/cli/manifest"
+	"github.com/pipego/cli/report"
 	"github.com/pipego/cli/runner"
+	"github.com/pipego/cli/scheduler"
+	"github.com/pipego/cli/state"
 )
 
 func TestInitConfig(t *testing.T) {
@@ -41,56 +51,494 @@ func TestInitDag(t *testing.T) {
 	c, err := initConfig(ctx, "../test/config/config.yml")
 	assert.Equal(t, nil, err)
 
-	_, err = initDag(ctx, c)
+	_, err = initDag(ctx, c, dag.Filter{})
 	assert.Equal(t, nil, err)
 }
 
-// nolint:dogsled
-func TestInitRunner(t *testing.T) {
+func TestLoadProto(t *testing.T) {
+	var data runner.Proto
+
+	err := loadProto("invalid.json", &data)
+	assert.NotEqual(t, nil, err)
+
+	err = loadProto("../test/config/invalid.yml", &data)
+	assert.NotEqual(t, nil, err)
+
+	err = loadProto("../test/data/runner.json", &data)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 4, len(data.Spec.Tasks))
+
+	var _data runner.Proto
+
+	err = loadProto("../test/data/runner.yml", &_data)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, data, _data)
+
+	var sched1, sched2 scheduler.Proto
+
+	err = loadProto("../test/data/scheduler1.json", &sched1)
+	assert.Equal(t, nil, err)
+
+	err = loadProto("../test/data/scheduler1.yml", &sched2)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, sched1, sched2)
+}
+
+func TestIsYAML(t *testing.T) {
+	assert.Equal(t, true, isYAML("runner.yml", []byte("{}")))
+	assert.Equal(t, true, isYAML("runner.YAML", []byte("{}")))
+	assert.Equal(t, false, isYAML("runner.json", []byte("kind: runner")))
+	assert.Equal(t, false, isYAML("runner", []byte("  {\"kind\": \"runner\"}")))
+	assert.Equal(t, true, isYAML("runner", []byte("kind: runner")))
+}
+
+func TestInitState(t *testing.T) {
+	store := state.New(t.TempDir())
+
+	st, err := initState(store, "pipeline", "")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "pipeline", st.Name)
+	assert.NotEqual(t, "", st.RunID)
+
+	_, err = initState(store, "pipeline", st.RunID)
+	assert.NotEqual(t, nil, err)
+
+	st.Tasks = []runner.TaskStatus{{Name: "task1", Status: runner.StatusSucceeded}}
+	err = store.Save(st)
+	assert.Equal(t, nil, err)
+
+	_st, err := initState(store, "pipeline", st.RunID)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, st.Tasks, _st.Tasks)
+}
+
+type pipelineTest struct {
+	err  error
+	tail chan *runner.TaskLine
+}
+
+func (p *pipelineTest) Init(_ context.Context) error {
+	return p.err
+}
+
+func (p *pipelineTest) Deinit(_ context.Context) error {
+	close(p.tail)
+	return nil
+}
+
+func (p *pipelineTest) Run(_ context.Context) error {
+	return nil
+}
+
+func (p *pipelineTest) Tail(_ context.Context) <-chan *runner.TaskLine {
+	return p.tail
+}
+
+type taskerTest struct {
+	runner.Tasker
+	status []runner.TaskStatus
+}
+
+func (t *taskerTest) Status(_ context.Context) []runner.TaskStatus {
+	return t.status
+}
+
+func TestRunState(t *testing.T) {
 	ctx := context.Background()
+	store := state.New(t.TempDir())
 
-	c, err := initConfig(ctx, "../test/config/config.yml")
+	st := &state.State{
+		Name:  "pipeline",
+		RunID: state.NewRunID(),
+		Tasks: []runner.TaskStatus{
+			{Name: "task1", Status: runner.StatusSucceeded},
+			{Name: "task2", Status: runner.StatusFailed},
+			{Name: "task3", Status: runner.StatusSkipped},
+		},
+	}
+
+	err := store.Save(st)
+	assert.Equal(t, nil, err)
+
+	resume := func() *state.State {
+		_st, err := initState(store, st.Name, st.RunID)
+		assert.Equal(t, nil, err)
+		return _st
+	}
+
+	// The state is kept if the tasker fails to init.
+	_st := resume()
+	tasker := &taskerTest{status: []runner.TaskStatus{{Name: "task1", Status: runner.StatusFailed}}}
+	err = runState(ctx, tasker, &pipelineTest{err: errors.New("unreachable"), tail: make(chan *runner.TaskLine)},
+		report.New("", ""), store, _st)
+	assert.NotEqual(t, nil, err)
+	assert.Equal(t, st.Tasks, resume().Tasks)
+
+	// The tasks filtered out are kept.
+	_st = resume()
+	tasker = &taskerTest{status: []runner.TaskStatus{{Name: "task2", Status: runner.StatusSucceeded}}}
+	err = runState(ctx, tasker, &pipelineTest{tail: make(chan *runner.TaskLine)}, report.New("", ""), store, _st)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []runner.TaskStatus{
+		{Name: "task1", Status: runner.StatusSucceeded},
+		{Name: "task2", Status: runner.StatusSucceeded},
+		{Name: "task3", Status: runner.StatusSkipped},
+	}, resume().Tasks)
+}
+
+func TestInitManifest(t *testing.T) {
+	ctx := context.Background()
+
+	_, err := initManifest(ctx, "invalid.yml", "", "", "", nil, true)
+	assert.NotEqual(t, nil, err)
+
+	_, err = initManifest(ctx, "", "", "invalid.json", "", nil, true)
+	assert.NotEqual(t, nil, err)
+
+	m, err := initManifest(ctx, "", "", "", "", nil, true)
+	assert.Equal(t, nil, err)
+	assert.NotEqual(t, nil, m.Require(manifest.KindConfig))
+
+	m, err = initManifest(ctx, "", "../test/config/config.yml", "../test/data/runner.json", "../test/data/scheduler1.json", nil, true)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, nil, m.Require(manifest.KindConfig, manifest.KindRunner, manifest.KindScheduler))
+
+	_m, err := initManifest(ctx, "../test/data/manifest.yml", "", "", "", nil, true)
 	assert.Equal(t, nil, err)
+	assert.Equal(t, m, _m)
 
-	d, err := initDag(ctx, c)
+	_m, err = initManifest(ctx, "../test/data/manifest.yml", "", "", "../test/data/scheduler2.json", nil, true)
 	assert.Equal(t, nil, err)
+	assert.Equal(t, []string{"ssd"}, _m.Scheduler.Spec.Task.NodeSelectors)
 
-	_, _, _, _, err = initRunner(ctx, c, "invalid.json", d)
+	name := filepath.Join(t.TempDir(), "runner.yml")
+	err = os.WriteFile(name, []byte("spec:\n  tasks:\n    - name: task1\n      timeout: 10 s\n"), 0o600)
+	assert.Equal(t, nil, err)
+
+	_, err = initManifest(ctx, "", "", name, "", nil, true)
 	assert.NotEqual(t, nil, err)
+	assert.Contains(t, err.Error(), `spec.tasks[0].timeout: invalid duration "10 s"`)
+
+	dir := t.TempDir()
+	err = os.WriteFile(filepath.Join(dir, "task1.sh"), []byte("echo task1\n"), 0o600)
+	assert.Equal(t, nil, err)
+
+	name = filepath.Join(dir, "runner.yml")
+	err = os.WriteFile(name, []byte("spec:\n  tasks:\n    - name: task1\n      file:\n        path: task1.sh\n"+
+		"      language:\n        name: bash\n"), 0o600)
+	assert.Equal(t, nil, err)
 
-	_, _, _, _, err = initRunner(ctx, c, "../test/data/runner.json", d)
+	m, err = initManifest(ctx, "", "", name, "", nil, true)
 	assert.Equal(t, nil, err)
+	assert.Equal(t, "echo task1", m.Runner.Spec.Tasks[0].File.Content)
 }
 
-func TestInitScheduler(t *testing.T) {
+func TestSetGlance(t *testing.T) {
+	g := runner.Glance{Dir: runner.GlanceDirReq{Path: "/"}, File: runner.GlanceFileReq{Path: "/etc/hostname", MaxSize: 1000}}
+
+	err := setGlance(&g)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "/", g.Dir.Path)
+
+	*glanceDir, *glanceMaxSize, *glanceSys, glanceSysSet, *glanceTimeout = "/tmp", 10, true, true, "30"
+
+	defer func() {
+		*glanceDir, *glanceMaxSize, *glanceSys, glanceSysSet, *glanceTimeout = "", 0, false, false, ""
+	}()
+
+	err = setGlance(&g)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, runner.Glance{
+		Dir:     runner.GlanceDirReq{Path: "/tmp"},
+		File:    runner.GlanceFileReq{Path: "/etc/hostname", MaxSize: 10},
+		Sys:     runner.GlanceSysReq{Enable: true},
+		Timeout: "30s",
+	}, g)
+
+	*glanceTimeout = "30 s"
+
+	err = setGlance(&g)
+	assert.NotEqual(t, nil, err)
+}
+
+func TestSetMaint(t *testing.T) {
+	m := runner.Maint{Clock: runner.MaintClockReq{Sync: true, Time: 1257894000}}
+
+	*maintClockSync, maintClockSyncSet, *maintTimeout = false, true, "1m"
+
+	defer func() {
+		*maintClockSync, maintClockSyncSet, *maintTimeout = false, false, ""
+	}()
+
+	err := setMaint(&m)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, runner.Maint{Clock: runner.MaintClockReq{Sync: false, Time: 1257894000}, Timeout: "1m0s"}, m)
+}
+
+func TestLoadVars(t *testing.T) {
+	vars, err := loadVars("", nil)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, map[string]string{}, vars)
+
+	_, err = loadVars("invalid.yml", nil)
+	assert.NotEqual(t, nil, err)
+
+	name := filepath.Join(t.TempDir(), "vars.json")
+	err = os.WriteFile(name, []byte(`{"env": "dev", "timeout": "10s"}`), 0o600)
+	assert.Equal(t, nil, err)
+
+	vars, err = loadVars(name, map[string]string{"env": "prod"})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, map[string]string{"env": "prod", "timeout": "10s"}, vars)
+
+	name = filepath.Join(t.TempDir(), "runner.yml")
+	err = os.WriteFile(name, []byte("spec:\n  vars:\n    env: dev\n  tasks:\n    - name: task-${{ .env }}\n      timeout: ${{ .timeout }}\n"+
+		"      commands:\n        - echo\n      language:\n        name: bash\n"), 0o600)
+	assert.Equal(t, nil, err)
+
+	m, err := initManifest(context.Background(), "", "", name, "", vars, true)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "task-prod", m.Runner.Spec.Tasks[0].Name)
+	assert.Equal(t, "10s", m.Runner.Spec.Tasks[0].Timeout)
+
+	// The commands not running tasks, e.g. glance, take no vars and drop the tasks instead.
+	_, err = initManifest(context.Background(), "", "", name, "", nil, true)
+	assert.NotEqual(t, nil, err)
+
+	m, err = initManifest(context.Background(), "", "", name, "", nil, false)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 0, len(m.Runner.Spec.Tasks))
+}
+
+func initTestManifest(t *testing.T) *manifest.Manifest {
+	m, err := initManifest(context.Background(), "../test/data/manifest.yml", "", "", "", nil, true)
+	assert.Equal(t, nil, err)
+
+	return m
+}
+
+func TestInitTasker(t *testing.T) {
 	ctx := context.Background()
+	m := initTestManifest(t)
 
-	c, err := initConfig(ctx, "../test/config/config.yml")
+	d, err := initDag(ctx, m.Config, dag.Filter{})
+	assert.Equal(t, nil, err)
+
+	s, err := initScheduler(ctx, m.Config, m.Scheduler)
+	assert.Equal(t, nil, err)
+
+	_, err = initTasker(ctx, m.Config, &runner.Proto{Spec: runner.Spec{Tasks: []runner.Task{{}}}}, d, s, nil, nil)
+	assert.NotEqual(t, nil, err)
+
+	_, err = initTasker(ctx, m.Config, m.Runner, d, s, nil, nil)
+	assert.Equal(t, nil, err)
+
+	dir := t.TempDir()
+	err = os.WriteFile(filepath.Join(dir, "task1.sh"), []byte("echo task1\n"), 0o600)
+	assert.Equal(t, nil, err)
+
+	name := filepath.Join(dir, "runner.yml")
+	err = os.WriteFile(name, []byte("spec:\n  tasks:\n    - name: task1\n      file:\n        path: task1.sh\n"+
+		"      language:\n        name: bash\n"), 0o600)
+	assert.Equal(t, nil, err)
+
+	_m, err := initManifest(ctx, "", "", name, "", nil, true)
+	assert.Equal(t, nil, err)
+
+	_, err = initGraph(ctx, _m.Runner, dag.Filter{})
+	assert.Equal(t, nil, err)
+
+	_, err = initTasker(ctx, m.Config, _m.Runner, d, s, nil, nil)
+	assert.Equal(t, nil, err)
+}
+
+func TestInitGlancer(t *testing.T) {
+	m := initTestManifest(t)
+
+	_, err := initGlancer(context.Background(), m.Config, m.Runner, nil)
+	assert.Equal(t, nil, err)
+}
+
+func TestInitMainter(t *testing.T) {
+	m := initTestManifest(t)
+
+	_, err := initMainter(context.Background(), m.Config, m.Runner, nil)
+	assert.Equal(t, nil, err)
+}
+
+func TestInitConfiger(t *testing.T) {
+	m := initTestManifest(t)
+
+	_, err := initConfiger(context.Background(), m.Config, m.Runner, nil)
+	assert.Equal(t, nil, err)
+}
+
+func TestInitPool(t *testing.T) {
+	m := initTestManifest(t)
+
+	p, err := initPool(context.Background(), m.Config)
 	assert.Equal(t, nil, err)
+	assert.NotEqual(t, nil, p)
+}
+
+func TestInitScheduler(t *testing.T) {
+	ctx := context.Background()
+	m := initTestManifest(t)
 
-	_, err = initScheduler(ctx, c, "invalid.json")
+	_, err := initScheduler(ctx, m.Config, &scheduler.Proto{})
 	assert.NotEqual(t, nil, err)
 
-	_, err = initScheduler(ctx, c, "../test/data/scheduler1.json")
+	_, err = initScheduler(ctx, m.Config, m.Scheduler)
 	assert.Equal(t, nil, err)
 }
 
-// nolint: dogsled
 func TestInitPipeline(t *testing.T) {
 	ctx := context.Background()
+	m := initTestManifest(t)
 
-	c, err := initConfig(ctx, "../test/config/config.yml")
+	d, err := initDag(ctx, m.Config, dag.Filter{})
+	assert.Equal(t, nil, err)
+
+	s, err := initScheduler(ctx, m.Config, m.Scheduler)
+	assert.Equal(t, nil, err)
+
+	_t, err := initTasker(ctx, m.Config, m.Runner, d, s, nil, nil)
+	assert.Equal(t, nil, err)
+
+	_, err = initPipeline(ctx, m.Config, _t, s)
+	assert.Equal(t, nil, err)
+}
+
+func TestInitGraph(t *testing.T) {
+	ctx := context.Background()
+	m := initTestManifest(t)
+
+	_, err := initGraph(ctx, &runner.Proto{Spec: runner.Spec{Tasks: []runner.Task{{}}}}, dag.Filter{})
+	assert.NotEqual(t, nil, err)
+
+	d, err := initGraph(ctx, m.Runner, dag.Filter{})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, len(m.Runner.Spec.Tasks), len(d.Graph(ctx).Vertex))
+
+	d, err = initGraph(ctx, m.Runner, dag.Filter{Targets: []string{"task3"}})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 3, len(d.Graph(ctx).Vertex))
+
+	_, err = initGraph(ctx, m.Runner, dag.Filter{Targets: []string{"task9"}})
+	assert.NotEqual(t, nil, err)
+}
+
+func TestLoadStatus(t *testing.T) {
+	status, err := loadStatus("")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 0, len(status))
+
+	_, err = loadStatus("invalid.json")
+	assert.NotEqual(t, nil, err)
+
+	name := filepath.Join(t.TempDir(), "report.json")
+	err = os.WriteFile(name, []byte(`{"tasks": [{"name": "task1", "status": "failed"}]}`), 0o600)
+	assert.Equal(t, nil, err)
+
+	status, err = loadStatus(name)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, map[string]string{"task1": "failed"}, status)
+}
+
+func TestValidateCommand(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "runner.yml")
+	err := os.WriteFile(name, []byte(`spec:
+  timeout: 1 h
+  tasks:
+    - name: task1
+      commands: [echo]
+      language: {name: bash}
+      timeout: 10 s
+      depends: [task2]
+    - name: task2
+      commands: [echo]
+      language: {name: bash}
+      depends: [task1]
+    - name: task2
+      commands: [echo]
+      language: {name: bash}
+      retries: -1
+      depends: [task1]
+`), 0o600)
 	assert.Equal(t, nil, err)
 
-	d, err := initDag(ctx, c)
+	*validateRunnerFile = name
+
+	defer func() {
+		*validateRunnerFile = ""
+	}()
+
+	r, w, err := os.Pipe()
 	assert.Equal(t, nil, err)
 
-	_t, _, _, _, err := initRunner(ctx, c, "../test/data/runner.json", d)
+	stdout := os.Stdout
+	os.Stdout = w
+
+	err = validateCommand(context.Background())
+
+	os.Stdout = stdout
+	_ = w.Close()
+
+	buf, _ := io.ReadAll(r)
+
+	assert.Equal(t, "found 5 problem(s)", err.Error())
+	assert.Equal(t, []string{
+		`runner: spec.tasks[0].timeout: invalid duration "10 s"`,
+		`runner: spec.tasks[2].name: duplicate task "task2" of spec.tasks[1]`,
+		`runner: spec.tasks[2].retries: negative value -1`,
+		`runner: spec.tasks: dependency cycle task1 -> task2 -> task1`,
+		`runner: spec.timeout: invalid duration "1 h"`,
+	}, strings.Split(strings.TrimSpace(string(buf)), "\n"))
+}
+
+func TestValidateCommandMatrix(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "runner.yml")
+	err := os.WriteFile(name, []byte(`spec:
+  tasks:
+    - name: task1
+      commands: [echo]
+      language: {name: bash}
+      matrix:
+        GO: ["1.22", "1.23"]
+    - name: task2
+      commands: [echo]
+      language: {name: bashh}
+      depends: [task1]
+`), 0o600)
 	assert.Equal(t, nil, err)
 
-	s, err := initScheduler(ctx, c, "../test/data/scheduler1.json")
+	*validateRunnerFile = name
+
+	defer func() {
+		*validateRunnerFile = ""
+	}()
+
+	r, w, err := os.Pipe()
 	assert.Equal(t, nil, err)
 
-	_, err = initPipeline(ctx, c, _t, s)
+	stdout := os.Stdout
+	os.Stdout = w
+
+	err = validateCommand(context.Background())
+
+	os.Stdout = stdout
+	_ = w.Close()
+
+	buf, _ := io.ReadAll(r)
+
+	assert.Equal(t, "found 1 problem(s)", err.Error())
+	assert.Contains(t, string(buf), "runner: spec.tasks[1].language.name")
+}
+
+func TestValidate(t *testing.T) {
+	err := validate(nil)
 	assert.Equal(t, nil, err)
+
+	err = validate([]error{errors.New("spec.tasks[0].name: name is empty"), errors.New("spec.nodes: nodes are empty")})
+	assert.Equal(t, "invalid: spec.tasks[0].name: name is empty; spec.nodes: nodes are empty", err.Error())
 }
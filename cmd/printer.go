@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/pipego/cli/runner"
+)
+
+const (
+	logExt  = ".log"
+	logEOF  = "EOF"
+	logPerm = 0o750
+)
+
+type printer struct {
+	attempts map[string]int
+	dir      string
+	files    map[string]*os.File
+	out      io.Writer
+}
+
+// newPrinter prints lines prefixed with the task name to out if not nil,
+// and appends them to one file per task in dir if not empty.
+func newPrinter(out io.Writer, dir string) (*printer, error) {
+	if dir != "" {
+		if err := os.MkdirAll(dir, logPerm); err != nil {
+			return nil, errors.Wrap(err, "failed to make dir")
+		}
+	}
+
+	return &printer{
+		attempts: map[string]int{},
+		dir:      dir,
+		files:    map[string]*os.File{},
+		out:      out,
+	}, nil
+}
+
+// Run consumes lines until log is closed, then closes the files and signals done.
+func (p *printer) Run(_ context.Context, log <-chan *runner.TaskLine, done chan<- error) {
+	var err error
+
+	for line := range log {
+		if line.Message == logEOF {
+			continue
+		}
+		if p.out != nil {
+			_, _ = fmt.Fprintf(p.out, "[%s] %s\n", label(line), line.Message)
+		}
+		if e := p.write(line); e != nil && err == nil {
+			err = e
+		}
+	}
+
+	for _, f := range p.files {
+		if e := f.Close(); e != nil && err == nil {
+			err = errors.Wrap(e, "failed to close file")
+		}
+	}
+
+	done <- err
+}
+
+// label is the task name, followed by the attempt if retried, e.g. task1#2.
+func label(line *runner.TaskLine) string {
+	if line.Attempt > 1 {
+		return line.Name + "#" + strconv.Itoa(line.Attempt)
+	}
+
+	return line.Name
+}
+
+func (p *printer) write(line *runner.TaskLine) error {
+	if p.dir == "" {
+		return nil
+	}
+
+	f, ok := p.files[line.Name]
+	if !ok {
+		var err error
+		name := strings.NewReplacer("/", "_", "\\", "_").Replace(line.Name) + logExt
+		f, err = os.OpenFile(filepath.Join(p.dir, name), os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+		if err != nil {
+			return errors.Wrap(err, "failed to open file")
+		}
+		p.files[line.Name] = f
+	}
+
+	if line.Attempt > 1 && line.Attempt != p.attempts[line.Name] {
+		if _, err := fmt.Fprintf(f, "--- attempt %d\n", line.Attempt); err != nil {
+			return errors.Wrap(err, "failed to write file")
+		}
+	}
+
+	p.attempts[line.Name] = line.Attempt
+
+	if _, err := fmt.Fprintln(f, line.Message); err != nil {
+		return errors.Wrap(err, "failed to write file")
+	}
+
+	return nil
+}
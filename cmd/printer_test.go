@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pipego/cli/runner"
+)
+
+func TestPrinter(t *testing.T) {
+	var buf bytes.Buffer
+
+	dir := t.TempDir()
+
+	p, err := newPrinter(&buf, dir)
+	assert.Equal(t, nil, err)
+
+	log := make(chan *runner.TaskLine, 7)
+	log <- &runner.TaskLine{Name: "task1", Attempt: 1, Pos: 1, Message: "line1"}
+	log <- &runner.TaskLine{Name: "task2", Attempt: 1, Pos: 1, Message: "line2"}
+	log <- &runner.TaskLine{Name: "task1", Attempt: 1, Pos: 2, Message: "line3"}
+	log <- &runner.TaskLine{Name: "task1", Attempt: 1, Pos: 3, Message: "EOF"}
+	log <- &runner.TaskLine{Name: "task2", Attempt: 1, Pos: 2, Message: "EOF"}
+	log <- &runner.TaskLine{Name: "task2", Attempt: 2, Pos: 1, Message: "line4"}
+	log <- &runner.TaskLine{Name: "task2", Attempt: 2, Pos: 2, Message: "EOF"}
+	close(log)
+
+	done := make(chan error, 1)
+	p.Run(context.Background(), log, done)
+	assert.Equal(t, nil, <-done)
+
+	assert.Equal(t, "[task1] line1\n[task2] line2\n[task1] line3\n[task2#2] line4\n", buf.String())
+
+	b, err := os.ReadFile(filepath.Join(dir, "task1.log"))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "line1\nline3\n", string(b))
+
+	b, err = os.ReadFile(filepath.Join(dir, "task2.log"))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "line2\n--- attempt 2\nline4\n", string(b))
+}
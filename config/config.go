@@ -17,8 +17,25 @@ type Spec struct {
 }
 
 type Server struct {
-	Host string `yaml:"host"`
-	Port int    `yaml:"port"`
+	Host    string `yaml:"host"`
+	Port    int    `yaml:"port"`
+	Timeout string `yaml:"timeout"`
+	Retry   Retry  `yaml:"retry"`
+	TLS     TLS    `yaml:"tls"`
+}
+
+type Retry struct {
+	Count      int    `yaml:"count"`
+	Backoff    string `yaml:"backoff"`
+	MaxBackoff string `yaml:"maxBackoff"`
+}
+
+type TLS struct {
+	Enable     bool   `yaml:"enable"`
+	CA         string `yaml:"ca"`
+	Cert       string `yaml:"cert"`
+	Key        string `yaml:"key"`
+	ServerName string `yaml:"serverName"`
 }
 
 var (
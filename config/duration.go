@@ -0,0 +1,70 @@
+package config
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ParseDuration parses s with units like 1h30m, or as plain seconds like 90,
+// and returns d if s is empty.
+func ParseDuration(s string, d time.Duration) (time.Duration, error) {
+	if s == "" {
+		return d, nil
+	}
+
+	duration, err := time.ParseDuration(s)
+	if err != nil {
+		seconds, e := strconv.ParseFloat(s, 64)
+		if e != nil {
+			return 0, errors.Errorf("invalid duration %q", s)
+		}
+		duration = time.Duration(seconds * float64(time.Second))
+	}
+
+	if duration < 0 {
+		return 0, errors.Errorf("negative duration %q", s)
+	}
+
+	return duration, nil
+}
+
+// ParseTimeout is ParseDuration which rejects zero, as a zero timeout expires at once.
+func ParseTimeout(s string, d time.Duration) (time.Duration, error) {
+	duration, err := ParseDuration(s, d)
+	if err != nil {
+		return 0, err
+	}
+
+	if duration == 0 {
+		return 0, errors.Errorf("zero duration %q", s)
+	}
+
+	return duration, nil
+}
+
+// Resolve checks the durations of c at load, and fails naming the first invalid field.
+func Resolve(c *Config) error {
+	servers := []struct {
+		path   string
+		server *Server
+	}{
+		{"spec.runner", &c.Spec.Runner},
+		{"spec.scheduler", &c.Spec.Scheduler},
+	}
+
+	for _, item := range servers {
+		if _, err := ParseTimeout(item.server.Timeout, DialTimeout); err != nil {
+			return errors.Wrap(err, item.path+".timeout")
+		}
+		if _, err := ParseDuration(item.server.Retry.Backoff, RetryBackoff); err != nil {
+			return errors.Wrap(err, item.path+".retry.backoff")
+		}
+		if _, err := ParseDuration(item.server.Retry.MaxBackoff, RetryMaxBackoff); err != nil {
+			return errors.Wrap(err, item.path+".retry.maxBackoff")
+		}
+	}
+
+	return nil
+}
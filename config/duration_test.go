@@ -0,0 +1,53 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDuration(t *testing.T) {
+	d, err := ParseDuration("", time.Minute)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, time.Minute, d)
+
+	d, err = ParseDuration("1h30m", time.Minute)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 90*time.Minute, d)
+
+	d, err = ParseDuration("90", time.Minute)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 90*time.Second, d)
+
+	d, err = ParseDuration("0.5", time.Minute)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 500*time.Millisecond, d)
+
+	d, err = ParseDuration("0", time.Minute)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, time.Duration(0), d)
+
+	_, err = ParseDuration("10 s", time.Minute)
+	assert.Equal(t, `invalid duration "10 s"`, err.Error())
+
+	_, err = ParseDuration("-1s", time.Minute)
+	assert.NotEqual(t, nil, err)
+}
+
+func TestParseTimeout(t *testing.T) {
+	d, err := ParseTimeout("", time.Minute)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, time.Minute, d)
+
+	_, err = ParseTimeout("0s", time.Minute)
+	assert.NotEqual(t, nil, err)
+}
+
+func TestResolve(t *testing.T) {
+	c := New()
+	assert.Equal(t, nil, Resolve(c))
+
+	c.Spec.Scheduler.Retry.MaxBackoff = "ten"
+	assert.Equal(t, `spec.scheduler.retry.maxBackoff: invalid duration "ten"`, Resolve(c).Error())
+}
@@ -0,0 +1,183 @@
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"math"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+const (
+	DialTimeout     = 10 * time.Second
+	RetryCount      = 3
+	RetryBackoff    = 1 * time.Second
+	RetryMaxBackoff = 10 * time.Second
+)
+
+const (
+	backoffJitter     = 0.2
+	backoffMultiplier = 1.6
+)
+
+type serviceConfig struct {
+	MethodConfig []methodConfig `json:"methodConfig"`
+}
+
+type methodConfig struct {
+	Name        []methodName `json:"name"`
+	RetryPolicy retryPolicy  `json:"retryPolicy"`
+}
+
+type methodName struct {
+	Service string `json:"service"`
+	Method  string `json:"method"`
+}
+
+type retryPolicy struct {
+	MaxAttempts          int      `json:"maxAttempts"`
+	InitialBackoff       string   `json:"initialBackoff"`
+	MaxBackoff           string   `json:"maxBackoff"`
+	BackoffMultiplier    float64  `json:"backoffMultiplier"`
+	RetryableStatusCodes []string `json:"retryableStatusCodes"`
+}
+
+// Address returns host:port of s.
+func (s *Server) Address() string {
+	return net.JoinHostPort(s.Host, strconv.Itoa(s.Port))
+}
+
+// DialTimeout returns the timeout to connect to s, which is DialTimeout if not set.
+func (s *Server) DialTimeout() (time.Duration, error) {
+	return ParseTimeout(s.Timeout, DialTimeout)
+}
+
+// DialOptions returns the options to connect to s with its credentials and
+// backoff, and to retry methods (full method names) on Unavailable errors.
+// Retry count defaults to RetryCount if zero and disables retries if negative.
+func (s *Server) DialOptions(methods ...string) ([]grpc.DialOption, error) {
+	creds, err := s.Credentials()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to init credentials")
+	}
+
+	timeout, err := s.DialTimeout()
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid timeout")
+	}
+
+	base, err := ParseDuration(s.Retry.Backoff, RetryBackoff)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid retry backoff")
+	}
+
+	limit, err := ParseDuration(s.Retry.MaxBackoff, RetryMaxBackoff)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid retry max backoff")
+	}
+
+	opts := []grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
+		grpc.WithConnectParams(grpc.ConnectParams{
+			Backoff: backoff.Config{
+				BaseDelay:  base,
+				Multiplier: backoffMultiplier,
+				Jitter:     backoffJitter,
+				MaxDelay:   limit,
+			},
+			MinConnectTimeout: timeout,
+		}),
+		grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(math.MaxInt32), grpc.MaxCallSendMsgSize(math.MaxInt32)),
+	}
+
+	count := s.Retry.Count
+	if count == 0 {
+		count = RetryCount
+	}
+
+	if count < 0 || len(methods) == 0 {
+		return opts, nil
+	}
+
+	c := methodConfig{
+		RetryPolicy: retryPolicy{
+			MaxAttempts:          count + 1,
+			InitialBackoff:       formatDuration(base),
+			MaxBackoff:           formatDuration(limit),
+			BackoffMultiplier:    backoffMultiplier,
+			RetryableStatusCodes: []string{"UNAVAILABLE"},
+		},
+	}
+
+	for _, item := range methods {
+		service, method, ok := strings.Cut(strings.TrimPrefix(item, "/"), "/")
+		if !ok {
+			return nil, errors.New("invalid method " + item)
+		}
+		c.Name = append(c.Name, methodName{Service: service, Method: method})
+	}
+
+	buf, err := json.Marshal(serviceConfig{MethodConfig: []methodConfig{c}})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal service config")
+	}
+
+	return append(opts, grpc.WithDefaultServiceConfig(string(buf))), nil
+}
+
+// Enabled reports whether TLS is enabled explicitly or implied by any of its settings.
+func (t *TLS) Enabled() bool {
+	return t.Enable || t.CA != "" || t.Cert != "" || t.Key != "" || t.ServerName != ""
+}
+
+// Credentials builds the transport credentials to connect to s, which are
+// TLS (or mTLS if cert and key set) if enabled, and insecure otherwise.
+func (s *Server) Credentials() (credentials.TransportCredentials, error) {
+	if !s.TLS.Enabled() {
+		return insecure.NewCredentials(), nil
+	}
+
+	c := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: s.TLS.ServerName,
+	}
+
+	if s.TLS.CA != "" {
+		buf, err := os.ReadFile(s.TLS.CA)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read ca")
+		}
+		c.RootCAs = x509.NewCertPool()
+		if !c.RootCAs.AppendCertsFromPEM(buf) {
+			return nil, errors.New("failed to parse ca")
+		}
+	}
+
+	if s.TLS.Cert != "" || s.TLS.Key != "" {
+		if s.TLS.Cert == "" || s.TLS.Key == "" {
+			return nil, errors.New("cert and key required both")
+		}
+		cert, err := tls.LoadX509KeyPair(s.TLS.Cert, s.TLS.Key)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to load cert and key")
+		}
+		c.Certificates = []tls.Certificate{cert}
+	}
+
+	return credentials.NewTLS(c), nil
+}
+
+// formatDuration formats d in seconds as required by service config, e.g. 1.5s.
+func formatDuration(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64) + "s"
+}
@@ -0,0 +1,227 @@
+package config
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	crt  string
+	pem  string
+}
+
+func newTestCert(t *testing.T, dir, name string, parent *testCert) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Equal(t, nil, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+
+	signer, signerKey := tmpl, key
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	assert.Equal(t, nil, err)
+
+	cert, err := x509.ParseCertificate(der)
+	assert.Equal(t, nil, err)
+
+	b, err := x509.MarshalECPrivateKey(key)
+	assert.Equal(t, nil, err)
+
+	c := &testCert{
+		cert: cert,
+		key:  key,
+		crt:  filepath.Join(dir, name+".crt"),
+		pem:  filepath.Join(dir, name+".key"),
+	}
+
+	err = os.WriteFile(c.crt, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600)
+	assert.Equal(t, nil, err)
+
+	err = os.WriteFile(c.pem, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: b}), 0o600)
+	assert.Equal(t, nil, err)
+
+	return c
+}
+
+func startTestServer(t *testing.T, ca, srv *testCert, mutual bool) Server {
+	cert, err := tls.LoadX509KeyPair(srv.crt, srv.pem)
+	assert.Equal(t, nil, err)
+
+	c := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if mutual {
+		c.ClientAuth = tls.RequireAndVerifyClientCert
+		c.ClientCAs = x509.NewCertPool()
+		c.ClientCAs.AddCert(ca.cert)
+	}
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Equal(t, nil, err)
+
+	s := grpc.NewServer(grpc.Creds(credentials.NewTLS(c)))
+	grpc_health_v1.RegisterHealthServer(s, health.NewServer())
+
+	go func() {
+		_ = s.Serve(lis)
+	}()
+
+	t.Cleanup(s.Stop)
+
+	_, port, _ := net.SplitHostPort(lis.Addr().String())
+	p, _ := strconv.Atoi(port)
+
+	return Server{Host: "127.0.0.1", Port: p}
+}
+
+func checkTestServer(s *Server) error {
+	creds, err := s.Credentials()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	conn, err := grpc.Dial(s.Host+":"+strconv.Itoa(s.Port), grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return err
+	}
+
+	defer func(conn *grpc.ClientConn) {
+		_ = conn.Close()
+	}(conn)
+
+	_, err = grpc_health_v1.NewHealthClient(conn).Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+
+	return err
+}
+
+func TestCredentials(t *testing.T) {
+	s := Server{}
+
+	creds, err := s.Credentials()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "insecure", creds.Info().SecurityProtocol)
+
+	s.TLS.Cert = "cert.pem"
+	_, err = s.Credentials()
+	assert.NotEqual(t, nil, err)
+
+	s.TLS = TLS{CA: "invalid.pem"}
+	_, err = s.Credentials()
+	assert.NotEqual(t, nil, err)
+
+	s.TLS = TLS{Enable: true}
+	creds, err = s.Credentials()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "tls", creds.Info().SecurityProtocol)
+}
+
+func TestCredentialsTLS(t *testing.T) {
+	dir := t.TempDir()
+
+	ca := newTestCert(t, dir, "ca", nil)
+	other := newTestCert(t, dir, "other", nil)
+	srv := newTestCert(t, dir, "server", ca)
+	cli := newTestCert(t, dir, "client", ca)
+
+	s := startTestServer(t, ca, srv, false)
+
+	s.TLS = TLS{CA: ca.crt}
+	assert.Equal(t, nil, checkTestServer(&s))
+
+	s.TLS = TLS{CA: ca.crt, ServerName: "localhost"}
+	assert.Equal(t, nil, checkTestServer(&s))
+
+	s.TLS = TLS{CA: other.crt}
+	assert.NotEqual(t, nil, checkTestServer(&s))
+
+	s = startTestServer(t, ca, srv, true)
+
+	s.TLS = TLS{CA: ca.crt, Cert: cli.crt, Key: cli.pem}
+	assert.Equal(t, nil, checkTestServer(&s))
+
+	s.TLS = TLS{CA: ca.crt}
+	assert.NotEqual(t, nil, checkTestServer(&s))
+
+	s.TLS = TLS{CA: ca.crt, Cert: other.crt, Key: other.pem}
+	assert.NotEqual(t, nil, checkTestServer(&s))
+}
+
+func TestDialOptions(t *testing.T) {
+	s := Server{Host: "127.0.0.1", Port: 29090}
+
+	assert.Equal(t, "127.0.0.1:29090", s.Address())
+
+	timeout, err := s.DialTimeout()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, DialTimeout, timeout)
+
+	opts, err := s.DialOptions()
+	assert.Equal(t, nil, err)
+
+	retry, err := s.DialOptions("/runner.ServerProto/SendConfig")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, len(opts)+1, len(retry))
+
+	// Invalid service config fails to dial.
+	conn, err := grpc.Dial(s.Address(), retry...)
+	assert.Equal(t, nil, err)
+	_ = conn.Close()
+
+	_, err = s.DialOptions("SendConfig")
+	assert.NotEqual(t, nil, err)
+
+	s.Retry.Count = -1
+	retry, err = s.DialOptions("/runner.ServerProto/SendConfig")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, len(opts), len(retry))
+
+	s.Timeout = "invalid"
+	_, err = s.DialOptions()
+	assert.NotEqual(t, nil, err)
+
+	s.Timeout = ""
+	s.Retry.Backoff = "invalid"
+	_, err = s.DialOptions()
+	assert.NotEqual(t, nil, err)
+}
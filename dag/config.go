@@ -12,6 +12,7 @@ type Task struct {
 	Width    int64
 	Language runner.Language
 	Depends  []string
+	Tags     []string
 }
 
 type Dag struct {
@@ -29,6 +30,6 @@ type Vertex struct {
 }
 
 type Edge struct {
-	From string
-	To   string
+	From string `json:"from"`
+	To   string `json:"to"`
 }
@@ -2,6 +2,9 @@ package dag
 
 import (
 	"context"
+	"strings"
+
+	"github.com/pkg/errors"
 
 	"github.com/pipego/cli/config"
 	"github.com/pipego/dag/runner"
@@ -11,10 +14,13 @@ type DAG interface {
 	Init(context.Context, []Task) error
 	Deinit(context.Context) error
 	Run(context.Context, func(string, runner.File, []runner.Param, []string, int64, runner.Language, runner.Log) error, runner.Log) error
+	Levels(context.Context) ([][]string, error)
+	Graph(context.Context) *Dag
 }
 
 type Config struct {
 	Config config.Config
+	Filter Filter
 }
 
 type dag struct {
@@ -35,6 +41,11 @@ func DefaultConfig() *Config {
 }
 
 func (d *dag) Init(_ context.Context, tasks []Task) error {
+	tasks, err := d.cfg.Filter.apply(tasks)
+	if err != nil {
+		return errors.Wrap(err, "failed to filter")
+	}
+
 	for index := range tasks {
 		v := Vertex{
 			Name:     tasks[index].Name,
@@ -75,3 +86,76 @@ func (d *dag) Run(_ context.Context, routine func(string, runner.File, []runner.
 
 	return d.runner.Run(log)
 }
+
+func (d *dag) Graph(_ context.Context) *Dag {
+	return &Dag{
+		Vertex: d.vertex,
+		Edge:   d.edge,
+	}
+}
+
+// Levels returns the vertex names in topological levels, where the vertexes of
+// one level depend on the previous levels only and run in parallel.
+// Names keep the order of tasks in each level.
+func (d *dag) Levels(_ context.Context) ([][]string, error) {
+	degree := map[string]int{}
+	next := map[string][]string{}
+
+	for i := range d.vertex {
+		degree[d.vertex[i].Name] = 0
+	}
+
+	for _, edge := range d.edge {
+		for _, name := range []string{edge.From, edge.To} {
+			if _, ok := degree[name]; !ok {
+				return nil, errors.New("unknown vertex " + name)
+			}
+		}
+		degree[edge.To]++
+		next[edge.From] = append(next[edge.From], edge.To)
+	}
+
+	var levels [][]string
+
+	done := 0
+	level := d.roots(degree)
+
+	for len(level) != 0 {
+		levels = append(levels, level)
+		done += len(level)
+		for _, name := range level {
+			for _, item := range next[name] {
+				degree[item]--
+			}
+		}
+		for _, name := range level {
+			degree[name] = -1
+		}
+		level = d.roots(degree)
+	}
+
+	if done != len(d.vertex) {
+		var buf []string
+		for i := range d.vertex {
+			if degree[d.vertex[i].Name] > 0 {
+				buf = append(buf, d.vertex[i].Name)
+			}
+		}
+		return nil, errors.New("dependency cycle in " + strings.Join(buf, ", "))
+	}
+
+	return levels, nil
+}
+
+// roots returns the vertexes of no dependency left, in the order of tasks.
+func (d *dag) roots(degree map[string]int) []string {
+	var buf []string
+
+	for i := range d.vertex {
+		if degree[d.vertex[i].Name] == 0 {
+			buf = append(buf, d.vertex[i].Name)
+		}
+	}
+
+	return buf
+}
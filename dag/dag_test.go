@@ -11,3 +11,37 @@ func TestDag(t *testing.T) {
 	p := New(context.Background(), DefaultConfig())
 	assert.NotEqual(t, nil, p)
 }
+
+func TestLevels(t *testing.T) {
+	ctx := context.Background()
+
+	d := New(ctx, DefaultConfig())
+	err := d.Init(ctx, []Task{
+		{Name: "task1"},
+		{Name: "task2"},
+		{Name: "task3", Depends: []string{"task1"}},
+		{Name: "task4", Depends: []string{"task1", "task2"}},
+		{Name: "task5", Depends: []string{"task4"}},
+	})
+	assert.Equal(t, nil, err)
+
+	levels, err := d.Levels(ctx)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, [][]string{{"task1", "task2"}, {"task3", "task4"}, {"task5"}}, levels)
+
+	d = New(ctx, DefaultConfig())
+	_ = d.Init(ctx, []Task{
+		{Name: "task1", Depends: []string{"task2"}},
+		{Name: "task2", Depends: []string{"task1"}},
+		{Name: "task3"},
+	})
+
+	_, err = d.Levels(ctx)
+	assert.Equal(t, "dependency cycle in task1, task2", err.Error())
+
+	d = New(ctx, DefaultConfig())
+	_ = d.Init(ctx, []Task{{Name: "task1", Depends: []string{"task2"}}})
+
+	_, err = d.Levels(ctx)
+	assert.NotEqual(t, nil, err)
+}
@@ -0,0 +1,156 @@
+package dag
+
+import (
+	"github.com/pkg/errors"
+)
+
+// Filter selects a subset of tasks, which is the intersection of the tasks
+// selected by each field if set, except the tasks of Skip.
+type Filter struct {
+	Targets []string
+	From    []string
+	Skip    []string
+	Tags    []string
+}
+
+func (f *Filter) empty() bool {
+	return len(f.Targets) == 0 && len(f.From) == 0 && len(f.Skip) == 0 && len(f.Tags) == 0
+}
+
+// apply returns the tasks selected by f in the order of tasks, with each depend out of
+// the selection replaced by its nearest selected ancestors, so that the order is kept.
+func (f *Filter) apply(tasks []Task) ([]Task, error) {
+	if f.empty() {
+		return tasks, nil
+	}
+
+	depends := map[string][]string{}
+	dependents := map[string][]string{}
+
+	for i := range tasks {
+		depends[tasks[i].Name] = tasks[i].Depends
+		for _, dep := range tasks[i].Depends {
+			dependents[dep] = append(dependents[dep], tasks[i].Name)
+		}
+	}
+
+	for _, names := range [][]string{f.Targets, f.From, f.Skip} {
+		for _, name := range names {
+			if _, ok := depends[name]; !ok {
+				return nil, errors.New("unknown task " + name)
+			}
+		}
+	}
+
+	selected := map[string]bool{}
+	for i := range tasks {
+		selected[tasks[i].Name] = true
+	}
+
+	intersect := func(set map[string]bool) {
+		for name := range selected {
+			if !set[name] {
+				delete(selected, name)
+			}
+		}
+	}
+
+	if len(f.Targets) != 0 {
+		intersect(closure(f.Targets, depends))
+	}
+
+	if len(f.From) != 0 {
+		intersect(closure(f.From, dependents))
+	}
+
+	if len(f.Tags) != 0 {
+		tagged := map[string]bool{}
+		for i := range tasks {
+			if hasAny(tasks[i].Tags, f.Tags) {
+				tagged[tasks[i].Name] = true
+			}
+		}
+		intersect(tagged)
+	}
+
+	for _, name := range f.Skip {
+		delete(selected, name)
+	}
+
+	var buf []Task
+
+	for i := range tasks {
+		if !selected[tasks[i].Name] {
+			continue
+		}
+		task := tasks[i]
+		task.Depends = ancestors(tasks[i].Depends, depends, selected)
+		buf = append(buf, task)
+	}
+
+	return buf, nil
+}
+
+// closure returns names and the names reachable from them by next.
+func closure(names []string, next map[string][]string) map[string]bool {
+	set := map[string]bool{}
+
+	var visit func(string)
+
+	visit = func(name string) {
+		if set[name] {
+			return
+		}
+		set[name] = true
+		for _, item := range next[name] {
+			visit(item)
+		}
+	}
+
+	for _, item := range names {
+		visit(item)
+	}
+
+	return set
+}
+
+// ancestors returns the selected of names, and the nearest selected ancestors by depends of the others.
+func ancestors(names []string, depends map[string][]string, selected map[string]bool) []string {
+	var buf []string
+
+	visited := map[string]bool{}
+
+	var visit func(string)
+
+	visit = func(name string) {
+		if visited[name] {
+			return
+		}
+		visited[name] = true
+		if selected[name] {
+			buf = append(buf, name)
+			return
+		}
+		for _, item := range depends[name] {
+			visit(item)
+		}
+	}
+
+	for _, item := range names {
+		visit(item)
+	}
+
+	return buf
+}
+
+func hasAny(tags, wanted []string) bool {
+	for _, item := range tags {
+		for _, want := range wanted {
+			if item == want {
+				return true
+			}
+		}
+	}
+
+	return false
+}
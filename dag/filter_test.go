@@ -0,0 +1,53 @@
+package dag
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilter(t *testing.T) {
+	tasks := []Task{
+		{Name: "task1", Tags: []string{"build"}},
+		{Name: "task2", Tags: []string{"build"}},
+		{Name: "task3", Depends: []string{"task1", "task2"}, Tags: []string{"test"}},
+		{Name: "task4", Depends: []string{"task3"}, Tags: []string{"deploy"}},
+		{Name: "task5", Depends: []string{"task1"}},
+	}
+
+	names := func(f Filter) []string {
+		buf, err := f.apply(tasks)
+		assert.Equal(t, nil, err)
+		var n []string
+		for i := range buf {
+			n = append(n, buf[i].Name)
+		}
+		return n
+	}
+
+	assert.Equal(t, []string{"task1", "task2", "task3", "task4", "task5"}, names(Filter{}))
+	assert.Equal(t, []string{"task1", "task2", "task3"}, names(Filter{Targets: []string{"task3"}}))
+	assert.Equal(t, []string{"task2", "task3", "task4"}, names(Filter{From: []string{"task2"}}))
+	assert.Equal(t, []string{"task2", "task3"}, names(Filter{From: []string{"task2"}, Targets: []string{"task3"}}))
+	assert.Equal(t, []string{"task1", "task3", "task4", "task5"}, names(Filter{Skip: []string{"task2"}}))
+	assert.Equal(t, []string{"task3", "task4"}, names(Filter{Tags: []string{"test", "deploy"}}))
+
+	buf, err := (&Filter{From: []string{"task3"}}).apply(tasks)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 0, len(buf[0].Depends))
+	assert.Equal(t, []string{"task3"}, buf[1].Depends)
+
+	buf, err = (&Filter{Skip: []string{"task3"}}).apply(tasks)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "task4", buf[2].Name)
+	assert.Equal(t, []string{"task1", "task2"}, buf[2].Depends)
+	assert.Equal(t, []string{"task1"}, buf[3].Depends)
+
+	buf, err = (&Filter{Tags: []string{"build", "deploy"}}).apply(tasks)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "task4", buf[2].Name)
+	assert.Equal(t, []string{"task1", "task2"}, buf[2].Depends)
+
+	_, err = (&Filter{Skip: []string{"task9"}}).apply(tasks)
+	assert.Equal(t, "unknown task task9", err.Error())
+}
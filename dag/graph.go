@@ -0,0 +1,154 @@
+package dag
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	FormatDOT     = "dot"
+	FormatMermaid = "mermaid"
+	FormatJSON    = "json"
+)
+
+var (
+	Formats = []string{FormatDOT, FormatMermaid, FormatJSON}
+
+	// Colors of vertexes by the status of tasks in a report.
+	Colors = map[string]string{
+		"succeeded": "#8fd19e",
+		"failed":    "#f28b82",
+		"skipped":   "#dadce0",
+		"canceled":  "#fdd663",
+		"running":   "#8ab4f8",
+	}
+)
+
+type graphJSON struct {
+	Nodes []graphNode `json:"nodes"`
+	Edges []Edge      `json:"edges"`
+}
+
+type graphNode struct {
+	Name   string `json:"name"`
+	Status string `json:"status,omitempty"`
+}
+
+// WriteGraph writes g in format, with the vertexes colored by status (name to status) if not empty.
+func WriteGraph(w io.Writer, format string, g *Dag, status map[string]string) error {
+	switch format {
+	case FormatDOT, "":
+		return writeDOT(w, g, status)
+	case FormatMermaid:
+		return writeMermaid(w, g, status)
+	case FormatJSON:
+		return writeJSON(w, g, status)
+	}
+
+	return errors.New("invalid format " + format)
+}
+
+func writeDOT(w io.Writer, g *Dag, status map[string]string) error {
+	var b strings.Builder
+
+	b.WriteString("digraph pipeline {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box, style=\"rounded,filled\", fillcolor=\"#ffffff\"];\n")
+
+	for i := range g.Vertex {
+		name := g.Vertex[i].Name
+		if color, ok := Colors[status[name]]; ok {
+			fmt.Fprintf(&b, "  %s [fillcolor=%q, tooltip=%q];\n", strconv.Quote(name), color, status[name])
+		} else {
+			fmt.Fprintf(&b, "  %s;\n", strconv.Quote(name))
+		}
+	}
+
+	for _, item := range g.Edge {
+		fmt.Fprintf(&b, "  %s -> %s;\n", strconv.Quote(item.From), strconv.Quote(item.To))
+	}
+
+	b.WriteString("}\n")
+
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return errors.Wrap(err, "failed to write")
+	}
+
+	return nil
+}
+
+func writeMermaid(w io.Writer, g *Dag, status map[string]string) error {
+	var b strings.Builder
+
+	// Vertexes are identified by index, as names may contain characters invalid in mermaid.
+	ids := map[string]string{}
+
+	b.WriteString("graph LR\n")
+
+	for i := range g.Vertex {
+		name := g.Vertex[i].Name
+		ids[name] = "t" + strconv.Itoa(i)
+		fmt.Fprintf(&b, "  %s[\"%s\"]\n", ids[name], strings.ReplaceAll(name, `"`, "#quot;"))
+	}
+
+	for _, item := range g.Edge {
+		fmt.Fprintf(&b, "  %s --> %s\n", ids[item.From], ids[item.To])
+	}
+
+	classes := map[string][]string{}
+
+	for i := range g.Vertex {
+		name := g.Vertex[i].Name
+		if _, ok := Colors[status[name]]; ok {
+			classes[status[name]] = append(classes[status[name]], ids[name])
+		}
+	}
+
+	keys := make([]string, 0, len(classes))
+	for key := range classes {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		fmt.Fprintf(&b, "  classDef %s fill:%s\n", key, Colors[key])
+		fmt.Fprintf(&b, "  class %s %s\n", strings.Join(classes[key], ","), key)
+	}
+
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return errors.Wrap(err, "failed to write")
+	}
+
+	return nil
+}
+
+func writeJSON(w io.Writer, g *Dag, status map[string]string) error {
+	buf := graphJSON{
+		Nodes: []graphNode{},
+		Edges: []Edge{},
+	}
+
+	for i := range g.Vertex {
+		buf.Nodes = append(buf.Nodes, graphNode{Name: g.Vertex[i].Name, Status: status[g.Vertex[i].Name]})
+	}
+
+	buf.Edges = append(buf.Edges, g.Edge...)
+
+	b, err := json.MarshalIndent(buf, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal")
+	}
+
+	if _, err := fmt.Fprintln(w, string(b)); err != nil {
+		return errors.Wrap(err, "failed to write")
+	}
+
+	return nil
+}
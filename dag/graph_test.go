@@ -0,0 +1,69 @@
+package dag
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func initGraph() *Dag {
+	return &Dag{
+		Vertex: []Vertex{{Name: "task1"}, {Name: "task2"}, {Name: `task "3"`}},
+		Edge:   []Edge{{From: "task1", To: "task2"}, {From: "task1", To: `task "3"`}},
+	}
+}
+
+func TestWriteDOT(t *testing.T) {
+	var buf bytes.Buffer
+
+	err := WriteGraph(&buf, FormatDOT, initGraph(), map[string]string{"task1": "succeeded", "task2": "failed"})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, `digraph pipeline {
+  rankdir=LR;
+  node [shape=box, style="rounded,filled", fillcolor="#ffffff"];
+  "task1" [fillcolor="#8fd19e", tooltip="succeeded"];
+  "task2" [fillcolor="#f28b82", tooltip="failed"];
+  "task \"3\"";
+  "task1" -> "task2";
+  "task1" -> "task \"3\"";
+}
+`, buf.String())
+}
+
+func TestWriteMermaid(t *testing.T) {
+	var buf bytes.Buffer
+
+	err := WriteGraph(&buf, FormatMermaid, initGraph(), map[string]string{"task1": "succeeded", "task2": "skipped",
+		`task "3"`: "skipped"})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, `graph LR
+  t0["task1"]
+  t1["task2"]
+  t2["task #quot;3#quot;"]
+  t0 --> t1
+  t0 --> t2
+  classDef skipped fill:#dadce0
+  class t1,t2 skipped
+  classDef succeeded fill:#8fd19e
+  class t0 succeeded
+`, buf.String())
+}
+
+func TestWriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+
+	err := WriteGraph(&buf, FormatJSON, initGraph(), map[string]string{"task1": "succeeded"})
+	assert.Equal(t, nil, err)
+
+	var g graphJSON
+	err = json.Unmarshal(buf.Bytes(), &g)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, graphNode{Name: "task1", Status: "succeeded"}, g.Nodes[0])
+	assert.Equal(t, graphNode{Name: "task2"}, g.Nodes[1])
+	assert.Equal(t, initGraph().Edge, g.Edges)
+
+	err = WriteGraph(&buf, "svg", initGraph(), nil)
+	assert.NotEqual(t, nil, err)
+}
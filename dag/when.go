@@ -0,0 +1,635 @@
+package dag
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	statusSucceeded = "succeeded"
+	statusFailed    = "failed"
+	statusCanceled  = "canceled"
+	statusSkipped   = "skipped"
+)
+
+// When is a condition of a task, e.g. failure() || params.ENV == 'prod', which is made of:
+//
+//	success(), failure(), always(): all depends succeeded, any failed (or canceled), true,
+//	  where a depend skipped after a failure, i.e. with a depend failed or skipped so, counts as failed,
+//	  and a depend skipped otherwise, e.g. by its condition, makes both success() and failure() false
+//	success('task1'), failure('task1'): the same of the tasks named instead of the depends
+//	params.NAME: value of the param NAME of the task
+//	tasks.NAME.status: status of the task NAME
+//	tasks.NAME.outputs.KEY: output KEY of the task NAME
+//	'string', "string", true, false
+//	==, !=, !, &&, || and parentheses
+//
+// A condition without success(), failure() or always() is checked as success() && condition,
+// as in GitHub Actions, so that it does not run the task after its depends failed.
+type When struct {
+	expr string
+	root node
+}
+
+// Env is what a condition is evaluated against.
+type Env struct {
+	Params  map[string]string
+	Depends []string
+	// Status of tasks finished, by name. Depends absent are ignored, e.g. filtered out.
+	Status  map[string]string
+	Outputs map[string]map[string]string
+	// Depends of tasks finished, by name, to find the failures before the depends skipped.
+	Graph map[string][]string
+}
+
+type node interface {
+	eval(*Env) (interface{}, error)
+	format() string
+}
+
+func ParseWhen(expr string) (*When, error) {
+	p := parser{expr: expr}
+
+	tokens, err := p.lex()
+	if err != nil {
+		return nil, err
+	}
+
+	p.tokens = tokens
+
+	root, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+
+	if !p.done() {
+		return nil, errors.Errorf("unexpected %q", p.peek().text)
+	}
+
+	return &When{expr: expr, root: root}, nil
+}
+
+func (w *When) Eval(env *Env) (bool, error) {
+	v, err := w.root.eval(env)
+	if err != nil {
+		return false, err
+	}
+
+	b, ok := v.(bool)
+	if !ok {
+		return false, errors.Errorf("%q is not a bool", w.expr)
+	}
+
+	if !b || w.checksStatus() {
+		return b, nil
+	}
+
+	return evalBool(&callNode{name: "success"}, env)
+}
+
+// checksStatus reports whether w has any of success(), failure() and always().
+func (w *When) checksStatus() bool {
+	var found bool
+
+	var walk func(node)
+
+	walk = func(n node) {
+		switch n := n.(type) {
+		case *unaryNode:
+			walk(n.x)
+		case *binaryNode:
+			walk(n.x)
+			walk(n.y)
+		case *callNode:
+			found = true
+		}
+	}
+
+	walk(w.root)
+
+	return found
+}
+
+// Tasks returns the names of tasks referred in w, which should be depended on to be finished.
+func (w *When) Tasks() []string {
+	var buf []string
+
+	var walk func(node)
+
+	walk = func(n node) {
+		switch n := n.(type) {
+		case *unaryNode:
+			walk(n.x)
+		case *binaryNode:
+			walk(n.x)
+			walk(n.y)
+		case *callNode:
+			buf = append(buf, n.args...)
+		case *refNode:
+			if n.scope == "tasks" {
+				buf = append(buf, n.name)
+			}
+		}
+	}
+
+	walk(w.root)
+
+	return buf
+}
+
+// Expand returns the condition with each task of names named in success() and failure() replaced by
+// its tasks, e.g. success('a') by success('a-1', 'a-2'). Other references to them are ambiguous and fail.
+func (w *When) Expand(names map[string][]string) (string, error) {
+	var err error
+
+	var walk func(node)
+
+	walk = func(n node) {
+		switch n := n.(type) {
+		case *unaryNode:
+			walk(n.x)
+		case *binaryNode:
+			walk(n.x)
+			walk(n.y)
+		case *callNode:
+			var args []string
+			for _, item := range n.args {
+				if buf, ok := names[item]; ok {
+					args = append(args, buf...)
+				} else {
+					args = append(args, item)
+				}
+			}
+			n.args = args
+		case *refNode:
+			if _, ok := names[n.name]; ok && n.scope == "tasks" && err == nil {
+				err = errors.Errorf("tasks.%s refers to the tasks of matrix, use success('%s') or failure('%s') instead",
+					n.name, n.name, n.name)
+			}
+		}
+	}
+
+	walk(w.root)
+
+	if err != nil {
+		return "", err
+	}
+
+	return w.root.format(), nil
+}
+
+func (w *When) String() string {
+	return w.expr
+}
+
+type literalNode struct {
+	value interface{}
+}
+
+func (n *literalNode) eval(_ *Env) (interface{}, error) {
+	return n.value, nil
+}
+
+func (n *literalNode) format() string {
+	if s, ok := n.value.(string); ok {
+		return quote(s)
+	}
+
+	return fmt.Sprint(n.value)
+}
+
+// quote quotes s by single quotes, or by double quotes if s has any single quote, as there is no escape.
+func quote(s string) string {
+	if strings.Contains(s, "'") {
+		return `"` + s + `"`
+	}
+
+	return "'" + s + "'"
+}
+
+type refNode struct {
+	scope string
+	name  string
+	// Output of the task name, or else the status.
+	output string
+}
+
+func (n *refNode) eval(env *Env) (interface{}, error) {
+	if n.scope == "params" {
+		return env.Params[n.name], nil
+	}
+
+	if n.output != "" {
+		return env.Outputs[n.name][n.output], nil
+	}
+
+	return env.Status[n.name], nil
+}
+
+func (n *refNode) format() string {
+	if n.scope == "params" {
+		return "params." + n.name
+	}
+
+	if n.output != "" {
+		return "tasks." + n.name + ".outputs." + n.output
+	}
+
+	return "tasks." + n.name + ".status"
+}
+
+type callNode struct {
+	name string
+	args []string
+}
+
+func (n *callNode) eval(env *Env) (interface{}, error) {
+	if n.name == "always" {
+		return true, nil
+	}
+
+	names := env.Depends
+	if len(n.args) != 0 {
+		names = n.args
+	}
+
+	succeeded, failed := true, false
+
+	for _, item := range names {
+		if _, ok := env.Status[item]; !ok {
+			// Tasks named are required to be finished, unlike depends.
+			succeeded = succeeded && len(n.args) == 0
+			continue
+		}
+		succeeded = succeeded && env.Status[item] == statusSucceeded
+		failed = failed || env.failed(item)
+	}
+
+	if n.name == "success" {
+		return succeeded, nil
+	}
+
+	return failed, nil
+}
+
+// failed reports whether task name failed or was canceled, or was skipped with such a depend.
+func (env *Env) failed(name string) bool {
+	switch env.Status[name] {
+	case statusFailed, statusCanceled:
+		return true
+	case statusSkipped:
+		for _, item := range env.Graph[name] {
+			if env.failed(item) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (n *callNode) format() string {
+	args := make([]string, 0, len(n.args))
+	for _, item := range n.args {
+		args = append(args, quote(item))
+	}
+
+	return n.name + "(" + strings.Join(args, ", ") + ")"
+}
+
+type unaryNode struct {
+	x node
+}
+
+func (n *unaryNode) eval(env *Env) (interface{}, error) {
+	x, err := evalBool(n.x, env)
+	if err != nil {
+		return nil, err
+	}
+
+	return !x, nil
+}
+
+func (n *unaryNode) format() string {
+	return "!(" + n.x.format() + ")"
+}
+
+type binaryNode struct {
+	op   string
+	x, y node
+}
+
+func (n *binaryNode) eval(env *Env) (interface{}, error) {
+	switch n.op {
+	case "&&", "||":
+		x, err := evalBool(n.x, env)
+		if err != nil {
+			return nil, err
+		}
+		if x == (n.op == "||") {
+			return x, nil
+		}
+		return evalBool(n.y, env)
+	}
+
+	x, err := n.x.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	y, err := n.y.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	if n.op == "==" {
+		return x == y, nil
+	}
+
+	return x != y, nil
+}
+
+func (n *binaryNode) format() string {
+	return "(" + n.x.format() + " " + n.op + " " + n.y.format() + ")"
+}
+
+func evalBool(n node, env *Env) (bool, error) {
+	v, err := n.eval(env)
+	if err != nil {
+		return false, err
+	}
+
+	b, ok := v.(bool)
+	if !ok {
+		return false, errors.Errorf("%q is not a bool", v)
+	}
+
+	return b, nil
+}
+
+type token struct {
+	kind string
+	text string
+}
+
+const (
+	tokenIdent  = "ident"
+	tokenOp     = "op"
+	tokenString = "string"
+)
+
+type parser struct {
+	expr   string
+	tokens []token
+	pos    int
+}
+
+func (p *parser) lex() ([]token, error) {
+	var buf []token
+
+	s := p.expr
+
+	for i := 0; i < len(s); {
+		c := rune(s[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '\'' || c == '"':
+			j := strings.IndexByte(s[i+1:], s[i])
+			if j < 0 {
+				return nil, errors.New("unterminated string " + s[i:])
+			}
+			buf = append(buf, token{kind: tokenString, text: s[i+1 : i+1+j]})
+			i += j + 2
+		case isIdent(c):
+			j := i
+			for j < len(s) && isIdent(rune(s[j])) {
+				j++
+			}
+			buf = append(buf, token{kind: tokenIdent, text: s[i:j]})
+			i = j
+		default:
+			op := ""
+			for _, item := range []string{"==", "!=", "&&", "||", "!", "(", ")", ",", "."} {
+				if strings.HasPrefix(s[i:], item) {
+					op = item
+					break
+				}
+			}
+			if op == "" {
+				return nil, errors.Errorf("unexpected %q", s[i])
+			}
+			buf = append(buf, token{kind: tokenOp, text: op})
+			i += len(op)
+		}
+	}
+
+	return buf, nil
+}
+
+func isIdent(c rune) bool {
+	return c == '_' || c == '-' || unicode.IsLetter(c) || unicode.IsDigit(c)
+}
+
+func (p *parser) done() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *parser) peek() token {
+	if p.done() {
+		return token{}
+	}
+
+	return p.tokens[p.pos]
+}
+
+func (p *parser) accept(kind, text string) bool {
+	if t := p.peek(); t.kind == kind && t.text == text {
+		p.pos++
+		return true
+	}
+
+	return false
+}
+
+func (p *parser) expect(kind, text string) error {
+	if !p.accept(kind, text) {
+		if p.done() {
+			return errors.Errorf("expected %q at end", text)
+		}
+		return errors.Errorf("expected %q but got %q", text, p.peek().text)
+	}
+
+	return nil
+}
+
+func (p *parser) or() (node, error) {
+	x, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.accept(tokenOp, "||") {
+		y, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		x = &binaryNode{op: "||", x: x, y: y}
+	}
+
+	return x, nil
+}
+
+func (p *parser) and() (node, error) {
+	x, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.accept(tokenOp, "&&") {
+		y, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		x = &binaryNode{op: "&&", x: x, y: y}
+	}
+
+	return x, nil
+}
+
+func (p *parser) unary() (node, error) {
+	if p.accept(tokenOp, "!") {
+		x, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{x: x}, nil
+	}
+
+	x, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, op := range []string{"==", "!="} {
+		if p.accept(tokenOp, op) {
+			y, err := p.operand()
+			if err != nil {
+				return nil, err
+			}
+			return &binaryNode{op: op, x: x, y: y}, nil
+		}
+	}
+
+	return x, nil
+}
+
+func (p *parser) operand() (node, error) {
+	if p.accept(tokenOp, "(") {
+		x, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(tokenOp, ")"); err != nil {
+			return nil, err
+		}
+		return x, nil
+	}
+
+	t := p.peek()
+	if p.done() {
+		return nil, errors.New("unexpected end")
+	}
+
+	p.pos++
+
+	switch t.kind {
+	case tokenString:
+		return &literalNode{value: t.text}, nil
+	case tokenIdent:
+	default:
+		return nil, errors.Errorf("unexpected %q", t.text)
+	}
+
+	switch t.text {
+	case "true", "false":
+		return &literalNode{value: t.text == "true"}, nil
+	case "success", "failure", "always":
+		return p.call(t.text)
+	case "params":
+		name, err := p.field()
+		if err != nil {
+			return nil, err
+		}
+		return &refNode{scope: "params", name: name}, nil
+	case "tasks":
+		name, err := p.field()
+		if err != nil {
+			return nil, err
+		}
+		field, err := p.field()
+		if err != nil {
+			return nil, err
+		}
+		switch field {
+		case "status":
+			return &refNode{scope: "tasks", name: name}, nil
+		case "outputs":
+			output, err := p.field()
+			if err != nil {
+				return nil, err
+			}
+			return &refNode{scope: "tasks", name: name, output: output}, nil
+		}
+		return nil, errors.Errorf("unknown field %q of task %q", field, name)
+	}
+
+	return nil, errors.Errorf("unknown name %q", t.text)
+}
+
+func (p *parser) call(name string) (node, error) {
+	if err := p.expect(tokenOp, "("); err != nil {
+		return nil, err
+	}
+
+	n := &callNode{name: name}
+
+	for !p.accept(tokenOp, ")") {
+		if len(n.args) != 0 {
+			if err := p.expect(tokenOp, ","); err != nil {
+				return nil, err
+			}
+		}
+		t := p.peek()
+		if t.kind != tokenString {
+			return nil, errors.Errorf("expected task name of %s() but got %q", name, t.text)
+		}
+		p.pos++
+		n.args = append(n.args, t.text)
+	}
+
+	if name == "always" && len(n.args) != 0 {
+		return nil, errors.New("always() takes no task")
+	}
+
+	return n, nil
+}
+
+// field returns the name after ".".
+func (p *parser) field() (string, error) {
+	if err := p.expect(tokenOp, "."); err != nil {
+		return "", err
+	}
+
+	t := p.peek()
+	if t.kind != tokenIdent {
+		return "", errors.Errorf("expected name after \".\" but got %q", t.text)
+	}
+
+	p.pos++
+
+	return t.text, nil
+}
@@ -0,0 +1,108 @@
+package dag
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWhen(t *testing.T) {
+	env := &Env{
+		Params:  map[string]string{"ENV": "prod"},
+		Depends: []string{"task1", "task2", "task9"},
+		Status: map[string]string{"task1": "succeeded", "task2": "failed", "task3": "succeeded", "task4": "skipped",
+			"task5": "skipped", "task6": "skipped", "task7": "canceled"},
+		Outputs: map[string]map[string]string{"task1": {"VERSION": "1.0"}},
+		Graph:   map[string][]string{"task4": {"task3"}, "task5": {"task3", "task6"}, "task6": {"task7"}},
+	}
+
+	eval := func(expr string) bool {
+		w, err := ParseWhen(expr)
+		assert.Equal(t, nil, err, expr)
+		b, err := w.Eval(env)
+		assert.Equal(t, nil, err, expr)
+		return b
+	}
+
+	assert.Equal(t, true, eval("always()"))
+	assert.Equal(t, false, eval("success()"))
+	assert.Equal(t, true, eval("failure()"))
+	assert.Equal(t, true, eval("success('task1', 'task3')"))
+	assert.Equal(t, false, eval("failure(\"task1\")"))
+	assert.Equal(t, false, eval("success('task9')"))
+	assert.Equal(t, false, eval("failure('task9')"))
+	assert.Equal(t, true, eval("always() && params.ENV == 'prod'"))
+	assert.Equal(t, true, eval("always() && tasks.task2.status == 'failed'"))
+	assert.Equal(t, true, eval("failure() && params.ENV == 'prod'"))
+	assert.Equal(t, true, eval("success() || !(params.ENV == 'dev')"))
+	assert.Equal(t, false, eval("!always() || false"))
+
+	// Without success(), failure() or always(), all depends are required to have succeeded.
+	assert.Equal(t, false, eval("params.ENV == 'prod'"))
+	assert.Equal(t, false, eval("tasks.task2.status == 'failed'"))
+
+	env.Status["task2"] = "succeeded"
+	assert.Equal(t, true, eval("success()"))
+	assert.Equal(t, false, eval("failure()"))
+	assert.Equal(t, true, eval("params.ENV == 'prod'"))
+	assert.Equal(t, false, eval("params.ENV != 'prod'"))
+	assert.Equal(t, true, eval("params.NONE == ''"))
+	assert.Equal(t, true, eval("tasks.task2.status == 'succeeded'"))
+	assert.Equal(t, true, eval("tasks.task1.outputs.VERSION == '1.0'"))
+	assert.Equal(t, true, eval("tasks.task2.outputs.VERSION == ''"))
+	assert.Equal(t, true, eval("true == true"))
+
+	// Skipped otherwise than after a failure is neither success nor failure.
+	env.Depends = append(env.Depends, "task4")
+	assert.Equal(t, false, eval("success()"))
+	assert.Equal(t, false, eval("failure()"))
+	assert.Equal(t, false, eval("failure('task4')"))
+
+	// Skipped after a failure, here the cancel of task7 before task6 and task5, is failure.
+	assert.Equal(t, false, eval("success('task5')"))
+	assert.Equal(t, true, eval("failure('task5')"))
+	assert.Equal(t, true, eval("failure('task6')"))
+
+	w, err := ParseWhen("failure('task1') || tasks.task2.status == 'failed'")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []string{"task1", "task2"}, w.Tasks())
+
+	w, err = ParseWhen("params.ENV")
+	assert.Equal(t, nil, err)
+	_, err = w.Eval(env)
+	assert.NotEqual(t, nil, err)
+
+	w, err = ParseWhen(`failure('a') && !success("b", 'c') || params.ENV == "it's"`)
+	assert.Equal(t, nil, err)
+	expr, err := w.Expand(map[string][]string{"a": {"a-1", "a-2"}, "b": {"b-1"}})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, `((failure('a-1', 'a-2') && !(success('b-1', 'c'))) || (params.ENV == "it's"))`, expr)
+
+	w, err = ParseWhen(expr)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []string{"a-1", "a-2", "b-1", "c"}, w.Tasks())
+
+	w, err = ParseWhen("tasks.a.outputs.VERSION == '1.0' && true")
+	assert.Equal(t, nil, err)
+	_, err = w.Expand(map[string][]string{"b": {"b-1"}})
+	assert.Equal(t, nil, err)
+	_, err = w.Expand(map[string][]string{"a": {"a-1"}})
+	assert.NotEqual(t, nil, err)
+
+	for _, expr := range []string{
+		"",
+		"success(",
+		"success() &&",
+		"success() success()",
+		"unknown()",
+		"always('task1')",
+		"params",
+		"tasks.task1.output",
+		"tasks.task1.outputs",
+		"params.ENV == 'prod",
+		"params.ENV = 'prod'",
+	} {
+		_, err := ParseWhen(expr)
+		assert.NotEqual(t, nil, err, expr)
+	}
+}
@@ -4,6 +4,7 @@ go 1.23
 
 require (
 	github.com/alecthomas/kingpin/v2 v2.4.0
+	github.com/klauspost/compress v1.17.9
 	github.com/pipego/dag v1.18.0
 	github.com/pkg/errors v0.9.1
 	github.com/stretchr/testify v1.8.4
@@ -3,12 +3,28 @@ package main
 import (
 	"context"
 	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/pipego/cli/cmd"
 )
 
 func main() {
-	if err := cmd.Run(context.Background()); err != nil {
-		fmt.Println(err.Error())
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+
+	// The first signal cancels ctx to stop gracefully, and the default
+	// handling is restored then so that the second one exits at once.
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	err := cmd.Run(ctx)
+	stop()
+
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
 	}
 }
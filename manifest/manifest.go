@@ -0,0 +1,136 @@
+package manifest
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+
+	"github.com/pipego/cli/config"
+	"github.com/pipego/cli/runner"
+	"github.com/pipego/cli/scheduler"
+)
+
+const (
+	KindConfig    = "cli"
+	KindRunner    = "runner"
+	KindScheduler = "scheduler"
+)
+
+// Manifest holds the documents of the cli config, the runner and the scheduler,
+// which are loaded from one multi-document file or from separate files.
+type Manifest struct {
+	Config    *config.Config
+	Runner    *runner.Proto
+	Scheduler *scheduler.Proto
+}
+
+func New() *Manifest {
+	return &Manifest{}
+}
+
+func Load(name string) (*Manifest, error) {
+	buf, err := os.ReadFile(name)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read")
+	}
+
+	return Parse(buf)
+}
+
+// Parse decodes the YAML documents separated by "---" in buf, and routes each
+// of them by kind.
+func Parse(buf []byte) (*Manifest, error) {
+	m := New()
+	d := yaml.NewDecoder(bytes.NewReader(buf))
+
+	for index := 0; ; index++ {
+		var node yaml.Node
+		if err := d.Decode(&node); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, errors.Wrapf(err, "failed to decode document %d", index)
+		}
+		if isEmpty(&node) {
+			continue
+		}
+		if err := m.route(&node); err != nil {
+			return nil, errors.Wrapf(err, "invalid document %d", index)
+		}
+	}
+
+	return m, nil
+}
+
+// Require checks that the documents of kinds exist.
+func (m *Manifest) Require(kinds ...string) error {
+	var missing []string
+
+	for _, item := range kinds {
+		switch item {
+		case KindConfig:
+			if m.Config == nil {
+				missing = append(missing, item)
+			}
+		case KindRunner:
+			if m.Runner == nil {
+				missing = append(missing, item)
+			}
+		case KindScheduler:
+			if m.Scheduler == nil {
+				missing = append(missing, item)
+			}
+		}
+	}
+
+	if len(missing) != 0 {
+		return errors.New("missing kind " + strings.Join(missing, ", "))
+	}
+
+	return nil
+}
+
+func (m *Manifest) route(node *yaml.Node) error {
+	var head struct {
+		Kind string `yaml:"kind"`
+	}
+
+	if err := node.Decode(&head); err != nil {
+		return errors.Wrap(err, "failed to decode kind")
+	}
+
+	switch head.Kind {
+	case KindConfig:
+		if m.Config != nil {
+			return errors.New("duplicate kind " + head.Kind)
+		}
+		m.Config = config.New()
+		return node.Decode(m.Config)
+	case KindRunner:
+		if m.Runner != nil {
+			return errors.New("duplicate kind " + head.Kind)
+		}
+		m.Runner = &runner.Proto{}
+		return node.Decode(m.Runner)
+	case KindScheduler:
+		if m.Scheduler != nil {
+			return errors.New("duplicate kind " + head.Kind)
+		}
+		m.Scheduler = &scheduler.Proto{}
+		return node.Decode(m.Scheduler)
+	}
+
+	return errors.New("unknown kind " + head.Kind)
+}
+
+func isEmpty(node *yaml.Node) bool {
+	if len(node.Content) == 0 {
+		return true
+	}
+
+	return node.Content[0].Kind == yaml.ScalarNode && node.Content[0].Tag == "!!null"
+}
@@ -0,0 +1,48 @@
+package manifest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoad(t *testing.T) {
+	_, err := Load("invalid.yml")
+	assert.NotEqual(t, nil, err)
+
+	m, err := Load("../test/data/manifest.yml")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, nil, m.Require(KindConfig, KindRunner, KindScheduler))
+	assert.Equal(t, 29090, m.Config.Spec.Runner.Port)
+	assert.Equal(t, 4, len(m.Runner.Spec.Tasks))
+	assert.Equal(t, 2, len(m.Scheduler.Spec.Nodes))
+}
+
+func TestParse(t *testing.T) {
+	m, err := Parse([]byte(`---
+kind: runner
+spec:
+  tasks:
+    - name: task1
+---
+---
+kind: scheduler
+spec:
+  nodes:
+    - name: node1
+`))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "task1", m.Runner.Spec.Tasks[0].Name)
+	assert.Equal(t, "node1", m.Scheduler.Spec.Nodes[0].Name)
+	assert.Equal(t, "missing kind cli", m.Require(KindConfig, KindRunner).Error())
+
+	_, err = Parse([]byte("kind: runner\n---\nkind: runner\n"))
+	assert.NotEqual(t, nil, err)
+
+	_, err = Parse([]byte("kind: cli\n---\nkind: unknown\n"))
+	assert.NotEqual(t, nil, err)
+	assert.Contains(t, err.Error(), "document 1")
+
+	_, err = Parse([]byte("kind: [cli\n"))
+	assert.NotEqual(t, nil, err)
+}
@@ -8,13 +8,13 @@ import (
 	"github.com/pipego/cli/config"
 	"github.com/pipego/cli/runner"
 	"github.com/pipego/cli/scheduler"
-	_runner "github.com/pipego/dag/runner"
 )
 
 type Pipeline interface {
 	Init(context.Context) error
 	Deinit(context.Context) error
-	Run(context.Context) (scheduler.Result, _runner.Log, error)
+	Run(context.Context) error
+	Tail(context.Context) <-chan *runner.TaskLine
 }
 
 type Config struct {
@@ -56,18 +56,14 @@ func (p *pipeline) Deinit(ctx context.Context) error {
 	return nil
 }
 
-func (p *pipeline) Run(ctx context.Context) (s scheduler.Result, l _runner.Log, e error) {
-	var err error
-
-	if s, err = p.cfg.Scheduler.Run(ctx); err != nil {
-		return scheduler.Result{}, _runner.Log{}, errors.Wrap(err, "failed to issuerail scheduler")
-	}
-
-	if err = p.cfg.Tasker.Run(ctx); err != nil {
-		return scheduler.Result{}, _runner.Log{}, errors.Wrap(err, "failed to run runner")
+func (p *pipeline) Run(ctx context.Context) error {
+	if err := p.cfg.Tasker.Run(ctx); err != nil {
+		return errors.Wrap(err, "failed to run runner")
 	}
 
-	l = p.cfg.Tasker.Tail(ctx)
+	return nil
+}
 
-	return s, l, nil
+func (p *pipeline) Tail(ctx context.Context) <-chan *runner.TaskLine {
+	return p.cfg.Tasker.Tail(ctx)
 }
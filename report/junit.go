@@ -0,0 +1,188 @@
+package report
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/pipego/cli/runner"
+)
+
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Name     string       `xml:"name,attr"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Skipped  int          `xml:"skipped,attr"`
+	Time     string       `xml:"time,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name      string      `xml:"name,attr"`
+	Tests     int         `xml:"tests,attr"`
+	Failures  int         `xml:"failures,attr"`
+	Skipped   int         `xml:"skipped,attr"`
+	Time      string      `xml:"time,attr"`
+	Timestamp string      `xml:"timestamp,attr,omitempty"`
+	Cases     []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+func writeJUnit(w io.Writer, r *Report) error {
+	seconds := func(d time.Duration) string {
+		return strconv.FormatFloat(d.Seconds(), 'f', 3, 64)
+	}
+
+	suites := junitSuites{
+		Name: r.Name,
+		Time: seconds(r.End.Sub(r.Start)),
+	}
+
+	if len(r.Tasks) != 0 {
+		suites.Suites = append(suites.Suites, junitTasks(r, seconds))
+	}
+
+	if s, err := junitRunner(r); err != nil {
+		return err
+	} else if len(s.Cases) != 0 {
+		suites.Suites = append(suites.Suites, s)
+	}
+
+	for _, item := range suites.Suites {
+		suites.Tests += item.Tests
+		suites.Failures += item.Failures
+		suites.Skipped += item.Skipped
+	}
+
+	buf, err := xml.MarshalIndent(suites, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal")
+	}
+
+	if _, err := fmt.Fprintln(w, xml.Header+string(buf)); err != nil {
+		return errors.Wrap(err, "failed to write")
+	}
+
+	return nil
+}
+
+func junitTasks(r *Report, seconds func(time.Duration) string) junitSuite {
+	logs := func(lines []runner.TaskOutput) string {
+		var buf []string
+		for _, item := range lines {
+			if item.Attempt > 1 {
+				buf = append(buf, fmt.Sprintf("[attempt %d] %s", item.Attempt, item.Message))
+			} else {
+				buf = append(buf, item.Message)
+			}
+		}
+		return strings.Join(buf, "\n")
+	}
+
+	suite := junitSuite{
+		Name:      r.Name + ".tasks",
+		Time:      seconds(r.End.Sub(r.Start)),
+		Timestamp: r.Start.Format(time.RFC3339),
+	}
+
+	for _, item := range r.Tasks {
+		c := junitCase{
+			Name:      item.Name,
+			ClassName: r.Name,
+			Time:      seconds(item.End.Sub(item.Start)),
+			SystemOut: logs(item.Log),
+		}
+		switch item.Status {
+		case runner.StatusSucceeded:
+		case runner.StatusFailed:
+			c.Failure = &junitMessage{Message: item.Error, Text: item.Error}
+			suite.Failures++
+		default:
+			c.Skipped = &junitMessage{Message: item.Status + ": " + item.Error}
+			suite.Skipped++
+		}
+		suite.Cases = append(suite.Cases, c)
+		suite.Tests++
+	}
+
+	return suite
+}
+
+func junitRunner(r *Report) (junitSuite, error) {
+	suite := junitSuite{
+		Name: r.Name + ".runner",
+		Time: "0.000",
+	}
+
+	add := func(name string, v interface{}, reason string) error {
+		buf, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return errors.Wrap(err, "failed to marshal")
+		}
+		c := junitCase{
+			Name:      name,
+			ClassName: r.Name,
+			Time:      "0.000",
+			SystemOut: string(buf),
+		}
+		if reason != "" {
+			c.Failure = &junitMessage{Message: reason, Text: reason}
+			suite.Failures++
+		}
+		suite.Cases = append(suite.Cases, c)
+		suite.Tests++
+		return nil
+	}
+
+	if r.Schedule != nil {
+		if err := add("scheduler", r.Schedule, r.Schedule.Error); err != nil {
+			return suite, err
+		}
+	}
+
+	if r.Glance != nil {
+		if err := add("glance", r.Glance, r.Glance.Error); err != nil {
+			return suite, err
+		}
+	}
+
+	if r.Maint != nil {
+		if err := add("maint", r.Maint, ""); err != nil {
+			return suite, err
+		}
+	}
+
+	if r.Config != nil {
+		if err := add("config", r.Config, ""); err != nil {
+			return suite, err
+		}
+	}
+
+	if r.Error != "" && len(r.Tasks) == 0 {
+		if err := add("cli", r.Name, r.Error); err != nil {
+			return suite, err
+		}
+	}
+
+	return suite, nil
+}
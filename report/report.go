@@ -0,0 +1,214 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/pipego/cli/runner"
+	"github.com/pipego/cli/scheduler"
+)
+
+const (
+	FormatText  = "text"
+	FormatJSON  = "json"
+	FormatJUnit = "junit"
+)
+
+var (
+	Formats = []string{FormatText, FormatJSON, FormatJUnit}
+)
+
+type Report struct {
+	Name     string              `json:"name"`
+	Version  string              `json:"version"`
+	RunID    string              `json:"runId,omitempty"`
+	Start    time.Time           `json:"start"`
+	End      time.Time           `json:"end"`
+	Duration string              `json:"duration"`
+	Error    string              `json:"error,omitempty"`
+	Schedule *scheduler.Result   `json:"schedule,omitempty"`
+	Plan     []runner.TaskPlan   `json:"plan,omitempty"`
+	Tasks    []runner.TaskStatus `json:"tasks,omitempty"`
+	Glance   *runner.GlanceReply `json:"glance,omitempty"`
+	Maint    *runner.MaintReply  `json:"maint,omitempty"`
+	Config   *runner.ConfigReply `json:"config,omitempty"`
+}
+
+func New(name, version string) *Report {
+	return &Report{
+		Name:    name,
+		Version: version,
+		Start:   time.Now(),
+	}
+}
+
+// Finish stamps the end of the report and records err, if any.
+func (r *Report) Finish(err error) {
+	r.End = time.Now()
+	r.Duration = r.End.Sub(r.Start).String()
+
+	if err != nil {
+		r.Error = err.Error()
+	}
+}
+
+func Write(w io.Writer, format string, r *Report) error {
+	switch format {
+	case FormatText, "":
+		return writeText(w, r)
+	case FormatJSON:
+		return writeJSON(w, r)
+	case FormatJUnit:
+		return writeJUnit(w, r)
+	}
+
+	return errors.New("invalid format " + format)
+}
+
+func writeJSON(w io.Writer, r *Report) error {
+	buf, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal")
+	}
+
+	if _, err := fmt.Fprintln(w, string(buf)); err != nil {
+		return errors.Wrap(err, "failed to write")
+	}
+
+	return nil
+}
+
+func writeText(w io.Writer, r *Report) error {
+	output := func(name string, v interface{}) error {
+		buf, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return errors.Wrap(err, "failed to marshal")
+		}
+		_, _ = fmt.Fprintln(w)
+		_, _ = fmt.Fprintln(w, "    Run:", name)
+		_, _ = fmt.Fprintln(w, " Output:", string(buf))
+		return nil
+	}
+
+	if r.Schedule != nil {
+		_, _ = fmt.Fprintln(w)
+		_, _ = fmt.Fprintln(w, "    Run: scheduler")
+		_, _ = fmt.Fprintln(w, "   Name:", r.Schedule.Name)
+		_, _ = fmt.Fprintln(w, "   Host:", r.Schedule.Host)
+		_, _ = fmt.Fprintln(w, "  Error:", r.Schedule.Error)
+	}
+
+	if len(r.Plan) != 0 {
+		writePlan(w, r.Plan)
+	}
+
+	if len(r.Tasks) != 0 {
+		_, _ = fmt.Fprintln(w)
+		_, _ = fmt.Fprintln(w, "    Run: summary")
+		if r.RunID != "" {
+			_, _ = fmt.Fprintln(w, "  RunID:", r.RunID)
+		}
+		for _, item := range r.Tasks {
+			_, _ = fmt.Fprintln(w, "   Task:", item.Name)
+			_, _ = fmt.Fprintln(w, " Status:", item.Status)
+			if item.Duration != "" {
+				_, _ = fmt.Fprintln(w, "   Time:", item.Duration)
+			}
+			if item.Resumed {
+				_, _ = fmt.Fprintln(w, " Resume:", true)
+			}
+			if item.Attempts > 1 {
+				_, _ = fmt.Fprintln(w, "  Tries:", item.Attempts)
+			}
+			if len(item.Outputs) != 0 {
+				_, _ = fmt.Fprintln(w, "Outputs:", outputs(item.Outputs))
+			}
+			if item.Error != "" {
+				_, _ = fmt.Fprintln(w, "  Error:", item.Error)
+			}
+		}
+	}
+
+	if r.Glance != nil {
+		if err := output("runner.glancer", r.Glance); err != nil {
+			return err
+		}
+	}
+
+	if r.Maint != nil {
+		if err := output("runner.mainter", r.Maint); err != nil {
+			return err
+		}
+	}
+
+	if r.Config != nil {
+		if err := output("runner.configer", r.Config); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func outputs(o map[string]string) string {
+	buf := make([]string, 0, len(o))
+	for key, val := range o {
+		buf = append(buf, key+"="+val)
+	}
+
+	sort.Strings(buf)
+
+	return strings.Join(buf, ", ")
+}
+
+func writePlan(w io.Writer, plan []runner.TaskPlan) {
+	params := func(p []runner.TaskParam) string {
+		var buf []string
+		for _, item := range p {
+			buf = append(buf, item.Name+"="+item.Value)
+		}
+		return strings.Join(buf, ", ")
+	}
+
+	_, _ = fmt.Fprintln(w)
+	_, _ = fmt.Fprintln(w, "    Run: plan")
+
+	level := 0
+
+	for i := range plan {
+		item := &plan[i]
+		if item.Level != level {
+			level = item.Level
+			_, _ = fmt.Fprintln(w, "  Level:", level)
+		}
+		_, _ = fmt.Fprintln(w, "   Task:", item.Name)
+		_, _ = fmt.Fprintln(w, "   Host:", item.Host)
+		if len(item.Depends) != 0 {
+			_, _ = fmt.Fprintln(w, "Depends:", strings.Join(item.Depends, ", "))
+		}
+		if item.When != "" {
+			_, _ = fmt.Fprintln(w, "   When:", item.When)
+		}
+		if item.Image != "" {
+			_, _ = fmt.Fprintln(w, "   Lang:", item.Language, item.Image)
+		} else {
+			_, _ = fmt.Fprintln(w, "   Lang:", item.Language)
+		}
+		if len(item.Params) != 0 {
+			_, _ = fmt.Fprintln(w, " Params:", params(item.Params))
+		}
+		if len(item.Commands) != 0 {
+			_, _ = fmt.Fprintln(w, "Command:", strings.Join(item.Commands, " "))
+		}
+		_, _ = fmt.Fprintln(w, "Timeout:", item.Timeout)
+		if item.Error != "" {
+			_, _ = fmt.Fprintln(w, "  Error:", item.Error)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pipego/cli/runner"
+)
+
+func initReport() *Report {
+	r := New("pipeline", "v1.0.0")
+
+	r.Tasks = []runner.TaskStatus{
+		{
+			Name:    "task1",
+			Status:  runner.StatusSucceeded,
+			Outputs: map[string]string{"VERSION": "1.0", "BUILD": "1"},
+			Log:     []runner.TaskOutput{{Pos: 1, Message: "task1"}, {Pos: 2, Message: "EOF"}},
+		},
+		{
+			Name:   "task2",
+			Status: runner.StatusFailed,
+			Error:  "exit status 1",
+		},
+		{
+			Name:   "task3",
+			Status: runner.StatusSkipped,
+			Error:  "depend task2 not succeeded",
+		},
+	}
+	r.Config = &runner.ConfigReply{Version: "v2.0.0"}
+	r.Finish(errors.New("failed tasks: task2"))
+
+	return r
+}
+
+func TestWriteText(t *testing.T) {
+	var buf bytes.Buffer
+
+	err := Write(&buf, FormatText, initReport())
+	assert.Equal(t, nil, err)
+	assert.Contains(t, buf.String(), " Status: failed")
+	assert.Contains(t, buf.String(), "Outputs: BUILD=1, VERSION=1.0")
+	assert.Contains(t, buf.String(), "    Run: runner.configer")
+}
+
+func TestWritePlan(t *testing.T) {
+	var buf bytes.Buffer
+
+	r := New("pipeline", "v1.0.0")
+	r.Plan = []runner.TaskPlan{
+		{Level: 1, Name: "task1", Host: "127.0.0.1:29090", Language: "bash", Commands: []string{"echo", "task1"}, Timeout: "10s"},
+		{Level: 1, Name: "task2", Host: "127.0.0.1:29090", Language: "bash", Timeout: "10s",
+			Params: []runner.TaskParam{{Name: "env", Value: "prod"}}},
+		{Level: 2, Name: "task3", Language: "go", Image: "golang:1.23", Depends: []string{"task1", "task2"}, Timeout: "10s",
+			Error: "no node"},
+	}
+	r.Finish(nil)
+
+	err := Write(&buf, FormatText, r)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, `
+    Run: plan
+  Level: 1
+   Task: task1
+   Host: 127.0.0.1:29090
+   Lang: bash
+Command: echo task1
+Timeout: 10s
+   Task: task2
+   Host: 127.0.0.1:29090
+   Lang: bash
+ Params: env=prod
+Timeout: 10s
+  Level: 2
+   Task: task3
+   Host: 
+Depends: task1, task2
+   Lang: go golang:1.23
+Timeout: 10s
+  Error: no node
+`, buf.String())
+}
+
+func TestWriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+
+	err := Write(&buf, FormatJSON, initReport())
+	assert.Equal(t, nil, err)
+
+	var r Report
+	err = json.Unmarshal(buf.Bytes(), &r)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "pipeline", r.Name)
+	assert.Equal(t, "failed tasks: task2", r.Error)
+	assert.Equal(t, 3, len(r.Tasks))
+	assert.Equal(t, "EOF", r.Tasks[0].Log[1].Message)
+	assert.Equal(t, "v2.0.0", r.Config.Version)
+}
+
+func TestWriteJUnit(t *testing.T) {
+	var buf bytes.Buffer
+
+	err := Write(&buf, FormatJUnit, initReport())
+	assert.Equal(t, nil, err)
+
+	var s junitSuites
+	err = xml.Unmarshal(buf.Bytes(), &s)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 4, s.Tests)
+	assert.Equal(t, 1, s.Failures)
+	assert.Equal(t, 1, s.Skipped)
+	assert.Equal(t, "task2", s.Suites[0].Cases[1].Name)
+	assert.Equal(t, "exit status 1", s.Suites[0].Cases[1].Failure.Message)
+	assert.Equal(t, "task1\nEOF", s.Suites[0].Cases[0].SystemOut)
+}
+
+func TestWriteInvalid(t *testing.T) {
+	var buf bytes.Buffer
+
+	err := Write(&buf, "invalid", initReport())
+	assert.NotEqual(t, nil, err)
+}
@@ -0,0 +1,63 @@
+package runner
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/pkg/errors"
+)
+
+const (
+	CompressGzip = "gzip"
+	CompressZstd = "zstd"
+
+	// CompressThreshold is the size of file.content above which it is gzipped if no compression is set.
+	CompressThreshold = 64 * 1024
+)
+
+// codec returns the compression of file with content of size, which is none if empty.
+func codec(file *TaskFile, size int) string {
+	switch {
+	case file.Zstd:
+		return CompressZstd
+	case file.Gzip, size > CompressThreshold:
+		return CompressGzip
+	}
+
+	return ""
+}
+
+// compress returns data compressed by codec, or data if codec is empty.
+func compress(data []byte, codec string) ([]byte, error) {
+	var b bytes.Buffer
+	var w io.WriteCloser
+
+	switch codec {
+	case "":
+		return data, nil
+	case CompressGzip:
+		w = gzip.NewWriter(&b)
+	case CompressZstd:
+		enc, err := zstd.NewWriter(&b)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to init zstd")
+		}
+		w = enc
+	default:
+		return nil, errors.New("invalid codec " + codec)
+	}
+
+	if _, err := w.Write(data); err != nil {
+		_ = w.Close()
+		return nil, errors.Wrap(err, "failed to write")
+	}
+
+	// The footer is written on close, so the buffer is complete only after it.
+	if err := w.Close(); err != nil {
+		return nil, errors.Wrap(err, "failed to close")
+	}
+
+	return b.Bytes(), nil
+}
@@ -0,0 +1,100 @@
+package runner
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func decompress(data []byte, gz, zst bool) ([]byte, error) {
+	var r io.Reader
+
+	switch {
+	case gz:
+		g, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		r = g
+	case zst:
+		z, err := zstd.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer z.Close()
+		r = z
+	default:
+		return data, nil
+	}
+
+	// A truncated stream fails with io.ErrUnexpectedEOF instead of io.EOF.
+	return io.ReadAll(r)
+}
+
+func TestCompress(t *testing.T) {
+	data := []byte(strings.Repeat("#!/usr/bin/env bash\necho compress\n", 100))
+
+	buf, err := compress(data, "")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, data, buf)
+
+	buf, err = compress(data, CompressGzip)
+	assert.Equal(t, nil, err)
+	assert.Less(t, len(buf), len(data))
+
+	_buf, err := decompress(buf, true, false)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, data, _buf)
+
+	_, err = decompress(buf[:len(buf)-8], true, false)
+	assert.Equal(t, true, errors.Is(err, io.ErrUnexpectedEOF))
+
+	buf, err = compress(data, CompressZstd)
+	assert.Equal(t, nil, err)
+	assert.Less(t, len(buf), len(data))
+
+	_buf, err = decompress(buf, false, true)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, data, _buf)
+
+	_, err = compress(data, "lz4")
+	assert.NotEqual(t, nil, err)
+}
+
+func TestCodec(t *testing.T) {
+	assert.Equal(t, "", codec(&TaskFile{}, 1))
+	assert.Equal(t, "", codec(&TaskFile{}, CompressThreshold))
+	assert.Equal(t, CompressGzip, codec(&TaskFile{}, CompressThreshold+1))
+	assert.Equal(t, CompressGzip, codec(&TaskFile{Gzip: true}, 1))
+	assert.Equal(t, CompressZstd, codec(&TaskFile{Zstd: true}, CompressThreshold+1))
+}
+
+func TestTaskerCompress(t *testing.T) {
+	small := "#!/usr/bin/env bash\necho small\n"
+	large := strings.Repeat("echo large\n", CompressThreshold/10)
+
+	srv := &runnerTest{}
+
+	_, err := runTasker(t, TaskerDefaultConfig(), srv, []Task{
+		{Name: "task1", File: TaskFile{Content: small}, Timeout: "10s"},
+		{Name: "task2", File: TaskFile{Content: small, Gzip: true}, Timeout: "10s"},
+		{Name: "task3", File: TaskFile{Content: small, Zstd: true}, Timeout: "10s"},
+		{Name: "task4", File: TaskFile{Content: large}, Timeout: "10s"},
+		{Name: "task5", File: TaskFile{Content: large, Zstd: true}, Timeout: "10s"},
+	})
+	assert.Equal(t, nil, err)
+
+	for _, item := range []string{"task1", "task2", "task3"} {
+		assert.Equal(t, small, srv.files[item], item)
+	}
+
+	for _, item := range []string{"task4", "task5"} {
+		assert.Equal(t, large, srv.files[item], item)
+	}
+}
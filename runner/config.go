@@ -1,209 +1,274 @@
 package runner
 
+import (
+	"time"
+)
+
 type Proto struct {
-	ApiVersion string   `json:"apiVersion"`
-	Kind       string   `json:"kind"`
-	Metadata   Metadata `json:"metadata"`
-	Spec       Spec     `json:"spec"`
+	ApiVersion string   `json:"apiVersion" yaml:"apiVersion"`
+	Kind       string   `json:"kind" yaml:"kind"`
+	Metadata   Metadata `json:"metadata" yaml:"metadata"`
+	Spec       Spec     `json:"spec" yaml:"spec"`
 }
 
 type Metadata struct {
-	Name string `json:"name"`
+	Name string `json:"name" yaml:"name"`
 }
 
 type Spec struct {
-	Tasks  []Task `json:"tasks"`
-	Glance Glance `json:"glance"`
-	Maint  Maint  `json:"maint"`
-	Config Config `json:"config"`
+	Vars    map[string]string `json:"vars" yaml:"vars"`
+	Tasks   []Task            `json:"tasks" yaml:"tasks"`
+	Timeout string            `json:"timeout" yaml:"timeout"`
+	Glance  Glance            `json:"glance" yaml:"glance"`
+	Maint   Maint             `json:"maint" yaml:"maint"`
+	Config  Config            `json:"config" yaml:"config"`
 }
 
 type Task struct {
-	Name     string       `json:"name"`
-	File     TaskFile     `json:"file"`
-	Params   []TaskParam  `json:"params"`
-	Commands []string     `json:"commands"`
-	Log      TaskLog      `json:"log"`
-	Language TaskLanguage `json:"language"`
-	Timeout  string       `json:"timeout"`
-	Depends  []string     `json:"depends"`
+	Name                   string       `json:"name" yaml:"name"`
+	File                   TaskFile     `json:"file" yaml:"file"`
+	Params                 []TaskParam  `json:"params" yaml:"params"`
+	Matrix                 TaskMatrix   `json:"matrix" yaml:"matrix"`
+	Commands               []string     `json:"commands" yaml:"commands"`
+	Outputs                []string     `json:"outputs" yaml:"outputs"`
+	Log                    TaskLog      `json:"log" yaml:"log"`
+	Language               TaskLanguage `json:"language" yaml:"language"`
+	Timeout                string       `json:"timeout" yaml:"timeout"`
+	Retries                int          `json:"retries" yaml:"retries"`
+	RetryDelay             string       `json:"retryDelay" yaml:"retryDelay"`
+	RetryOn                string       `json:"retryOn" yaml:"retryOn"`
+	Depends                []string     `json:"depends" yaml:"depends"`
+	When                   string       `json:"when" yaml:"when"`
+	Tags                   []string     `json:"tags" yaml:"tags"`
+	NodeName               string       `json:"nodeName" yaml:"nodeName"`
+	NodeSelectors          []string     `json:"nodeSelectors" yaml:"nodeSelectors"`
+	RequestedResource      TaskResource `json:"requestedResource" yaml:"requestedResource"`
+	ToleratesUnschedulable bool         `json:"toleratesUnschedulable" yaml:"toleratesUnschedulable"`
 }
 
 type TaskFile struct {
-	Content string `json:"content"`
-	Gzip    bool   `json:"gzip"`
+	Path    string `json:"path" yaml:"path"`
+	Content string `json:"content" yaml:"content"`
+	Gzip    bool   `json:"gzip" yaml:"gzip"`
+	Zstd    bool   `json:"zstd" yaml:"zstd"`
 }
 
+// TaskMatrix is the values of each param to run a task with, in all combinations.
+type TaskMatrix map[string][]string
+
 type TaskParam struct {
-	Name  string `json:"name"`
-	Value string `json:"value"`
+	Name  string `json:"name" yaml:"name"`
+	Value string `json:"value" yaml:"value"`
 }
 
 type TaskLog struct {
-	Width int64 `json:"width"`
+	Width int64 `json:"width" yaml:"width"`
 }
 
 type TaskLanguage struct {
-	Name     string       `json:"name"`
-	Artifact TaskArtifact `json:"artifact"`
+	Name     string       `json:"name" yaml:"name"`
+	Artifact TaskArtifact `json:"artifact" yaml:"artifact"`
 }
 
 type TaskArtifact struct {
-	Image   string `json:"image"`
-	User    string `json:"user"`
-	Pass    string `json:"pass"`
-	Cleanup bool   `json:"cleanup"`
+	Image   string `json:"image" yaml:"image"`
+	User    string `json:"user" yaml:"user"`
+	Pass    string `json:"pass" yaml:"pass"`
+	Cleanup bool   `json:"cleanup" yaml:"cleanup"`
+}
+
+type TaskResource struct {
+	MilliCPU int64 `json:"milliCPU" yaml:"milliCPU"`
+	Memory   int64 `json:"memory" yaml:"memory"`
+	Storage  int64 `json:"storage" yaml:"storage"`
+}
+
+type TaskStatus struct {
+	Name     string            `json:"name" yaml:"name"`
+	Status   string            `json:"status" yaml:"status"`
+	Host     string            `json:"host" yaml:"host"`
+	Start    time.Time         `json:"start" yaml:"start"`
+	End      time.Time         `json:"end" yaml:"end"`
+	Duration string            `json:"duration" yaml:"duration"`
+	Error    string            `json:"error" yaml:"error"`
+	Attempts int               `json:"attempts" yaml:"attempts"`
+	Resumed  bool              `json:"resumed" yaml:"resumed"`
+	Outputs  map[string]string `json:"outputs" yaml:"outputs"`
+	Log      []TaskOutput      `json:"log" yaml:"log"`
+}
+
+type TaskPlan struct {
+	Level    int         `json:"level" yaml:"level"`
+	Name     string      `json:"name" yaml:"name"`
+	Host     string      `json:"host" yaml:"host"`
+	Depends  []string    `json:"depends" yaml:"depends"`
+	When     string      `json:"when" yaml:"when"`
+	Language string      `json:"language" yaml:"language"`
+	Image    string      `json:"image" yaml:"image"`
+	Params   []TaskParam `json:"params" yaml:"params"`
+	Commands []string    `json:"commands" yaml:"commands"`
+	Timeout  string      `json:"timeout" yaml:"timeout"`
+	Error    string      `json:"error" yaml:"error"`
 }
 
 type TaskResult struct {
-	Output TaskOutput `json:"output"`
-	Error  string     `json:"error"`
+	Output TaskOutput `json:"output" yaml:"output"`
+	Error  string     `json:"error" yaml:"error"`
 }
 
 type TaskOutput struct {
-	Pos     int64  `json:"pos"`
-	Time    int64  `json:"time"`
-	Message string `json:"message"`
+	Attempt int    `json:"attempt" yaml:"attempt"`
+	Pos     int64  `json:"pos" yaml:"pos"`
+	Time    int64  `json:"time" yaml:"time"`
+	Message string `json:"message" yaml:"message"`
+}
+
+type TaskLine struct {
+	Name    string `json:"name" yaml:"name"`
+	Attempt int    `json:"attempt" yaml:"attempt"`
+	Pos     int64  `json:"pos" yaml:"pos"`
+	Time    int64  `json:"time" yaml:"time"`
+	Message string `json:"message" yaml:"message"`
 }
 
 type Glance struct {
-	Dir     GlanceDirReq  `json:"dir"`
-	File    GlanceFileReq `json:"file"`
-	Sys     GlanceSysReq  `json:"sys"`
-	Timeout string        `json:"timeout"`
+	Dir     GlanceDirReq  `json:"dir" yaml:"dir"`
+	File    GlanceFileReq `json:"file" yaml:"file"`
+	Sys     GlanceSysReq  `json:"sys" yaml:"sys"`
+	Timeout string        `json:"timeout" yaml:"timeout"`
 }
 
 type GlanceDirReq struct {
-	Path string `json:"path"`
+	Path string `json:"path" yaml:"path"`
 }
 
 type GlanceFileReq struct {
-	Path    string `json:"path"`
-	MaxSize int64  `json:"maxSize"`
+	Path    string `json:"path" yaml:"path"`
+	MaxSize int64  `json:"maxSize" yaml:"maxSize"`
 }
 
 type GlanceSysReq struct {
-	Enable bool `json:"enable"`
+	Enable bool `json:"enable" yaml:"enable"`
 }
 
 type GlanceReply struct {
-	Dir   GlanceDirRep  `json:"dir"`
-	File  GlanceFileRep `json:"file"`
-	Sys   GlanceSysRep  `json:"sys"`
-	Error string        `json:"error"`
+	Dir   GlanceDirRep  `json:"dir" yaml:"dir"`
+	File  GlanceFileRep `json:"file" yaml:"file"`
+	Sys   GlanceSysRep  `json:"sys" yaml:"sys"`
+	Error string        `json:"error" yaml:"error"`
 }
 
 type GlanceDirRep struct {
-	Entries []GlanceEntry `json:"entries"`
+	Entries []GlanceEntry `json:"entries" yaml:"entries"`
 }
 
 type GlanceEntry struct {
-	Name  string `json:"name"`
-	IsDir bool   `json:"isDir"`
-	Size  int64  `json:"size"`
-	Time  string `json:"time"`
-	User  string `json:"user"`
-	Group string `json:"group"`
-	Mode  string `json:"mode"`
+	Name  string `json:"name" yaml:"name"`
+	IsDir bool   `json:"isDir" yaml:"isDir"`
+	Size  int64  `json:"size" yaml:"size"`
+	Time  string `json:"time" yaml:"time"`
+	User  string `json:"user" yaml:"user"`
+	Group string `json:"group" yaml:"group"`
+	Mode  string `json:"mode" yaml:"mode"`
 }
 
 type GlanceFileRep struct {
-	Content  string `json:"content"`
-	Readable bool   `json:"readable"`
+	Content  string `json:"content" yaml:"content"`
+	Readable bool   `json:"readable" yaml:"readable"`
 }
 
 type GlanceSysRep struct {
-	Resource GlanceResource `json:"resource"`
-	Stats    GlanceStats    `json:"stats"`
+	Resource GlanceResource `json:"resource" yaml:"resource"`
+	Stats    GlanceStats    `json:"stats" yaml:"stats"`
 }
 
 type GlanceResource struct {
-	Allocatable GlanceAllocatable `json:"allocatable"`
-	Requested   GlanceRequested   `json:"requested"`
+	Allocatable GlanceAllocatable `json:"allocatable" yaml:"allocatable"`
+	Requested   GlanceRequested   `json:"requested" yaml:"requested"`
 }
 
 type GlanceAllocatable struct {
-	MilliCPU int64 `json:"milliCPU"`
-	Memory   int64 `json:"memory"`
-	Storage  int64 `json:"storage"`
+	MilliCPU int64 `json:"milliCPU" yaml:"milliCPU"`
+	Memory   int64 `json:"memory" yaml:"memory"`
+	Storage  int64 `json:"storage" yaml:"storage"`
 }
 
 type GlanceRequested struct {
-	MilliCPU int64 `json:"milliCPU"`
-	Memory   int64 `json:"memory"`
-	Storage  int64 `json:"storage"`
+	MilliCPU int64 `json:"milliCPU" yaml:"milliCPU"`
+	Memory   int64 `json:"memory" yaml:"memory"`
+	Storage  int64 `json:"storage" yaml:"storage"`
 }
 
 type GlanceStats struct {
-	CPU       GlanceCPU       `json:"cpu"`
-	Host      string          `json:"host"`
-	Memory    GlanceMemory    `json:"memory"`
-	OS        string          `json:"os"`
-	Storage   GlanceStorage   `json:"storage"`
-	Processes []GlanceProcess `json:"processes"`
+	CPU       GlanceCPU       `json:"cpu" yaml:"cpu"`
+	Host      string          `json:"host" yaml:"host"`
+	Memory    GlanceMemory    `json:"memory" yaml:"memory"`
+	OS        string          `json:"os" yaml:"os"`
+	Storage   GlanceStorage   `json:"storage" yaml:"storage"`
+	Processes []GlanceProcess `json:"processes" yaml:"processes"`
 }
 
 type GlanceCPU struct {
-	Total string `json:"total"`
-	Used  string `json:"used"`
+	Total string `json:"total" yaml:"total"`
+	Used  string `json:"used" yaml:"used"`
 }
 
 type GlanceMemory struct {
-	Total string `json:"total"`
-	Used  string `json:"used"`
+	Total string `json:"total" yaml:"total"`
+	Used  string `json:"used" yaml:"used"`
 }
 
 type GlanceStorage struct {
-	Total string `json:"total"`
-	Used  string `json:"used"`
+	Total string `json:"total" yaml:"total"`
+	Used  string `json:"used" yaml:"used"`
 }
 
 type GlanceProcess struct {
-	Process GlanceThread   `json:"process"`
-	Threads []GlanceThread `json:"threads"`
+	Process GlanceThread   `json:"process" yaml:"process"`
+	Threads []GlanceThread `json:"threads" yaml:"threads"`
 }
 
 type GlanceThread struct {
-	Name    string  `json:"name"`
-	Cmdline string  `json:"cmdline"`
-	Memory  int64   `json:"memory"`
-	Time    float64 `json:"time"`
-	Pid     int64   `json:"pid"`
+	Name    string  `json:"name" yaml:"name"`
+	Cmdline string  `json:"cmdline" yaml:"cmdline"`
+	Memory  int64   `json:"memory" yaml:"memory"`
+	Time    float64 `json:"time" yaml:"time"`
+	Pid     int64   `json:"pid" yaml:"pid"`
 }
 
 type Maint struct {
-	Clock   MaintClockReq `json:"clock"`
-	Timeout string        `json:"timeout"`
+	Clock   MaintClockReq `json:"clock" yaml:"clock"`
+	Timeout string        `json:"timeout" yaml:"timeout"`
 }
 
 type MaintClockReq struct {
-	Sync bool  `json:"sync"`
-	Time int64 `json:"time"`
+	Sync bool  `json:"sync" yaml:"sync"`
+	Time int64 `json:"time" yaml:"time"`
 }
 
 type MaintReply struct {
-	Clock MaintClockRep `json:"clock"`
+	Clock MaintClockRep `json:"clock" yaml:"clock"`
 }
 
 type MaintClockRep struct {
-	Sync MaintClockSync `json:"sync"`
-	Diff MaintClockDiff `json:"diff"`
+	Sync MaintClockSync `json:"sync" yaml:"sync"`
+	Diff MaintClockDiff `json:"diff" yaml:"diff"`
 }
 
 type MaintClockSync struct {
-	Status string `json:"status"`
+	Status string `json:"status" yaml:"status"`
 }
 
 type MaintClockDiff struct {
-	Time      int64 `json:"time"`
-	Dangerous bool  `json:"dangerous"`
+	Time      int64 `json:"time" yaml:"time"`
+	Dangerous bool  `json:"dangerous" yaml:"dangerous"`
 }
 
 type Config struct {
-	Version bool   `json:"version"`
-	Timeout string `json:"timeout"`
+	Version bool   `json:"version" yaml:"version"`
+	Timeout string `json:"timeout" yaml:"timeout"`
 }
 
 type ConfigReply struct {
-	Version string `json:"version"`
+	Version string `json:"version" yaml:"version"`
 }
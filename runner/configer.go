@@ -2,13 +2,8 @@ package runner
 
 import (
 	"context"
-	"math"
-	"strconv"
-	"time"
 
 	"github.com/pkg/errors"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 
 	"github.com/pipego/cli/config"
 	proto "github.com/pipego/cli/runner/proto"
@@ -23,12 +18,12 @@ type Configer interface {
 type ConfigerConfig struct {
 	Config config.Config
 	Data   Proto
+	Pool   Pool
 }
 
 type configer struct {
 	cfg    *ConfigerConfig
 	client proto.ServerProtoClient
-	conn   *grpc.ClientConn
 }
 
 func ConfigerNew(_ context.Context, cfg *ConfigerConfig) Configer {
@@ -49,9 +44,7 @@ func (c *configer) Init(ctx context.Context) error {
 	return nil
 }
 
-func (c *configer) Deinit(ctx context.Context) error {
-	_ = c.deinitConn(ctx)
-
+func (c *configer) Deinit(_ context.Context) error {
 	return nil
 }
 
@@ -62,18 +55,23 @@ func (c *configer) Run(ctx context.Context) (rep ConfigReply, err error) {
 		}
 	}
 
-	ctx, cancel := context.WithTimeout(ctx, c.setTimeout(c.cfg.Data.Spec.Config.Timeout))
+	timeout, e := config.ParseTimeout(c.cfg.Data.Spec.Config.Timeout, ConfigTimeout)
+	if e != nil {
+		return rep, errors.Wrap(e, "invalid spec.config.timeout")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
 	reply, e := c.client.SendConfig(ctx)
-	defer func() {
-		_ = reply.CloseSend()
-	}()
-
 	if e != nil {
 		return rep, errors.Wrap(e, "failed to set")
 	}
 
+	defer func() {
+		_ = reply.CloseSend()
+	}()
+
 	if e = reply.Send(&proto.ConfigRequest{
 		ApiVersion: c.cfg.Data.ApiVersion,
 		Kind:       c.cfg.Data.Kind,
@@ -97,31 +95,15 @@ func (c *configer) Run(ctx context.Context) (rep ConfigReply, err error) {
 	return output(recv), nil
 }
 
-func (c *configer) initConn(_ context.Context) error {
-	var err error
+func (c *configer) initConn(ctx context.Context) error {
+	host := c.cfg.Config.Spec.Runner.Address()
 
-	host := c.cfg.Config.Spec.Runner.Host
-	port := c.cfg.Config.Spec.Runner.Port
-
-	c.conn, err = grpc.Dial(host+":"+strconv.Itoa(port),
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
-		grpc.WithBlock(),
-		grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(math.MaxInt32), grpc.MaxCallSendMsgSize(math.MaxInt32)))
+	conn, err := c.cfg.Pool.Get(ctx, host)
 	if err != nil {
-		return errors.Wrap(err, "failed to dial")
+		return errors.Wrap(err, "failed to get conn")
 	}
 
-	c.client = proto.NewServerProtoClient(c.conn)
+	c.client = proto.NewServerProtoClient(conn)
 
 	return nil
 }
-
-func (c *configer) deinitConn(_ context.Context) error {
-	return c.conn.Close()
-}
-
-func (c *configer) setTimeout(timeout string) time.Duration {
-	duration, _ := time.ParseDuration(timeout)
-
-	return duration
-}
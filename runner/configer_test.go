@@ -5,9 +5,68 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	proto "github.com/pipego/cli/runner/proto"
 )
 
+type configerTest struct {
+	proto.UnimplementedServerProtoServer
+	calls int
+}
+
+func (c *configerTest) SendConfig(srv proto.ServerProto_SendConfigServer) error {
+	c.calls++
+	if c.calls == 1 {
+		return status.Error(codes.Unavailable, "unavailable")
+	}
+
+	if _, err := srv.Recv(); err != nil {
+		return err
+	}
+
+	return srv.Send(&proto.ConfigReply{Version: "v1.0.0"})
+}
+
 func TestConfiger(t *testing.T) {
 	m := ConfigerNew(context.Background(), ConfigerDefaultConfig())
 	assert.NotEqual(t, nil, m)
 }
+
+func TestConfigerRetry(t *testing.T) {
+	ctx := context.Background()
+
+	srv := &configerTest{}
+
+	c := ConfigerDefaultConfig()
+	c.Config.Spec.Runner = startRunner(t, srv)
+	c.Config.Spec.Runner.Retry.Backoff = "10ms"
+	c.Data.Spec.Config.Timeout = "10s"
+	c.Pool = PoolNew(ctx, &PoolConfig{Config: c.Config})
+
+	defer func() {
+		_ = c.Pool.Deinit(ctx)
+	}()
+
+	_c := ConfigerNew(ctx, c)
+	assert.Equal(t, nil, _c.Init(ctx))
+
+	rep, err := _c.Run(ctx)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "v1.0.0", rep.Version)
+	assert.Equal(t, 2, srv.calls)
+
+	srv.calls = 0
+	c.Config.Spec.Runner.Retry.Count = -1
+	c.Pool = PoolNew(ctx, &PoolConfig{Config: c.Config})
+
+	_c = ConfigerNew(ctx, c)
+	assert.Equal(t, nil, _c.Init(ctx))
+
+	_, err = _c.Run(ctx)
+	assert.NotEqual(t, nil, err)
+	assert.Equal(t, 1, srv.calls)
+
+	_ = c.Pool.Deinit(ctx)
+}
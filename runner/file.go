@@ -0,0 +1,63 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ReadFiles sets file.content of tasks to the files of file.path, which is a path or glob relative to dir
+// if not absolute, and clears file.path as it is read. The files matched by a glob are joined in the order of names.
+func ReadFiles(data *Proto, dir string) error {
+	for i := range data.Spec.Tasks {
+		file := &data.Spec.Tasks[i].File
+		if file.Path == "" {
+			continue
+		}
+		path := fmt.Sprintf("spec.tasks[%d].file.path", i)
+		if file.Content != "" {
+			return errors.New(path + ": file.content is set too")
+		}
+		content, err := readFiles(file.Path, dir)
+		if err != nil {
+			return errors.Wrap(err, path)
+		}
+		file.Content = content
+		file.Path = ""
+	}
+
+	return nil
+}
+
+func readFiles(pattern, dir string) (string, error) {
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(dir, pattern)
+	}
+
+	names, err := filepath.Glob(pattern)
+	if err != nil {
+		return "", errors.Wrap(err, "invalid pattern")
+	}
+
+	if len(names) == 0 {
+		return "", errors.New("no file matched " + pattern)
+	}
+
+	sort.Strings(names)
+
+	var buf []string
+
+	for _, name := range names {
+		b, err := os.ReadFile(name)
+		if err != nil {
+			return "", errors.Wrap(err, "failed to read")
+		}
+		buf = append(buf, strings.TrimSuffix(string(b), "\n"))
+	}
+
+	return strings.Join(buf, "\n"), nil
+}
@@ -0,0 +1,62 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	err := os.MkdirAll(filepath.Join(dir, "scripts"), 0o750)
+	assert.Equal(t, nil, err)
+
+	for name, content := range map[string]string{
+		"scripts/1-setup.sh": "#!/usr/bin/env bash\necho setup\n",
+		"scripts/2-build.sh": "echo build\n",
+		"scripts/task.py":    "print('task')\n",
+	} {
+		err = os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600)
+		assert.Equal(t, nil, err)
+	}
+
+	data := Proto{
+		Spec: Spec{
+			Tasks: []Task{
+				{Name: "task1", File: TaskFile{Path: "scripts/*.sh", Gzip: true}},
+				{Name: "task2", File: TaskFile{Path: filepath.Join(dir, "scripts", "task.py")}},
+				{Name: "task3", File: TaskFile{Content: "echo task3"}},
+			},
+		},
+	}
+
+	err = ReadFiles(&data, dir)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "#!/usr/bin/env bash\necho setup\necho build", data.Spec.Tasks[0].File.Content)
+	assert.Equal(t, "", data.Spec.Tasks[0].File.Path)
+	assert.Equal(t, true, data.Spec.Tasks[0].File.Gzip)
+	assert.Equal(t, "print('task')", data.Spec.Tasks[1].File.Content)
+	assert.Equal(t, "echo task3", data.Spec.Tasks[2].File.Content)
+
+	err = ReadFiles(&data, dir)
+	assert.Equal(t, nil, err)
+
+	data.Spec.Tasks = []Task{{Name: "task1", File: TaskFile{Path: "scripts/1-setup.sh", Content: "echo task1"}}}
+
+	err = ReadFiles(&data, dir)
+	assert.NotEqual(t, nil, err)
+	assert.Contains(t, err.Error(), "spec.tasks[0].file.path")
+
+	data.Spec.Tasks = []Task{{Name: "task1", File: TaskFile{Path: "scripts/*.go"}}}
+
+	err = ReadFiles(&data, dir)
+	assert.NotEqual(t, nil, err)
+
+	data.Spec.Tasks = []Task{{Name: "task1", File: TaskFile{Path: "scripts/[.sh"}}}
+
+	err = ReadFiles(&data, dir)
+	assert.NotEqual(t, nil, err)
+}
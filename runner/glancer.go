@@ -2,13 +2,8 @@ package runner
 
 import (
 	"context"
-	"math"
-	"strconv"
-	"time"
 
 	"github.com/pkg/errors"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 
 	"github.com/pipego/cli/config"
 	proto "github.com/pipego/cli/runner/proto"
@@ -23,12 +18,12 @@ type Glancer interface {
 type GlancerConfig struct {
 	Config config.Config
 	Data   Proto
+	Pool   Pool
 }
 
 type glancer struct {
 	cfg    *GlancerConfig
 	client proto.ServerProtoClient
-	conn   *grpc.ClientConn
 }
 
 func GlancerNew(_ context.Context, cfg *GlancerConfig) Glancer {
@@ -49,9 +44,7 @@ func (g *glancer) Init(ctx context.Context) error {
 	return nil
 }
 
-func (g *glancer) Deinit(ctx context.Context) error {
-	_ = g.deinitConn(ctx)
-
+func (g *glancer) Deinit(_ context.Context) error {
 	return nil
 }
 
@@ -148,18 +141,23 @@ func (g *glancer) Run(ctx context.Context) (rep GlanceReply, err error) {
 		}
 	}
 
-	ctx, cancel := context.WithTimeout(ctx, g.setTimeout(g.cfg.Data.Spec.Glance.Timeout))
+	timeout, e := config.ParseTimeout(g.cfg.Data.Spec.Glance.Timeout, GlanceTimeout)
+	if e != nil {
+		return rep, errors.Wrap(e, "invalid spec.glance.timeout")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
 	reply, e := g.client.SendGlance(ctx)
-	defer func() {
-		_ = reply.CloseSend()
-	}()
-
 	if e != nil {
 		return rep, errors.Wrap(e, "failed to set")
 	}
 
+	defer func() {
+		_ = reply.CloseSend()
+	}()
+
 	if e = reply.Send(&proto.GlanceRequest{
 		ApiVersion: g.cfg.Data.ApiVersion,
 		Kind:       g.cfg.Data.Kind,
@@ -192,31 +190,15 @@ func (g *glancer) Run(ctx context.Context) (rep GlanceReply, err error) {
 	return output(recv), nil
 }
 
-func (g *glancer) initConn(_ context.Context) error {
-	var err error
+func (g *glancer) initConn(ctx context.Context) error {
+	host := g.cfg.Config.Spec.Runner.Address()
 
-	host := g.cfg.Config.Spec.Runner.Host
-	port := g.cfg.Config.Spec.Runner.Port
-
-	g.conn, err = grpc.Dial(host+":"+strconv.Itoa(port),
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
-		grpc.WithBlock(),
-		grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(math.MaxInt32), grpc.MaxCallSendMsgSize(math.MaxInt32)))
+	conn, err := g.cfg.Pool.Get(ctx, host)
 	if err != nil {
-		return errors.Wrap(err, "failed to dial")
+		return errors.Wrap(err, "failed to get conn")
 	}
 
-	g.client = proto.NewServerProtoClient(g.conn)
+	g.client = proto.NewServerProtoClient(conn)
 
 	return nil
 }
-
-func (g *glancer) deinitConn(_ context.Context) error {
-	return g.conn.Close()
-}
-
-func (g *glancer) setTimeout(timeout string) time.Duration {
-	duration, _ := time.ParseDuration(timeout)
-
-	return duration
-}
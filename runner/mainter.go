@@ -2,13 +2,8 @@ package runner
 
 import (
 	"context"
-	"math"
-	"strconv"
-	"time"
 
 	"github.com/pkg/errors"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 
 	"github.com/pipego/cli/config"
 	proto "github.com/pipego/cli/runner/proto"
@@ -23,12 +18,12 @@ type Mainter interface {
 type MainterConfig struct {
 	Config config.Config
 	Data   Proto
+	Pool   Pool
 }
 
 type mainter struct {
 	cfg    *MainterConfig
 	client proto.ServerProtoClient
-	conn   *grpc.ClientConn
 }
 
 func MainterNew(_ context.Context, cfg *MainterConfig) Mainter {
@@ -49,9 +44,7 @@ func (m *mainter) Init(ctx context.Context) error {
 	return nil
 }
 
-func (m *mainter) Deinit(ctx context.Context) error {
-	_ = m.deinitConn(ctx)
-
+func (m *mainter) Deinit(_ context.Context) error {
 	return nil
 }
 
@@ -70,18 +63,23 @@ func (m *mainter) Run(ctx context.Context) (rep MaintReply, err error) {
 		}
 	}
 
-	ctx, cancel := context.WithTimeout(ctx, m.setTimeout(m.cfg.Data.Spec.Maint.Timeout))
+	timeout, e := config.ParseTimeout(m.cfg.Data.Spec.Maint.Timeout, MaintTimeout)
+	if e != nil {
+		return rep, errors.Wrap(e, "invalid spec.maint.timeout")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
 	reply, e := m.client.SendMaint(ctx)
-	defer func() {
-		_ = reply.CloseSend()
-	}()
-
 	if e != nil {
 		return rep, errors.Wrap(e, "failed to set")
 	}
 
+	defer func() {
+		_ = reply.CloseSend()
+	}()
+
 	if e = reply.Send(&proto.MaintRequest{
 		ApiVersion: m.cfg.Data.ApiVersion,
 		Kind:       m.cfg.Data.Kind,
@@ -108,31 +106,15 @@ func (m *mainter) Run(ctx context.Context) (rep MaintReply, err error) {
 	return output(recv), nil
 }
 
-func (m *mainter) initConn(_ context.Context) error {
-	var err error
+func (m *mainter) initConn(ctx context.Context) error {
+	host := m.cfg.Config.Spec.Runner.Address()
 
-	host := m.cfg.Config.Spec.Runner.Host
-	port := m.cfg.Config.Spec.Runner.Port
-
-	m.conn, err = grpc.Dial(host+":"+strconv.Itoa(port),
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
-		grpc.WithBlock(),
-		grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(math.MaxInt32), grpc.MaxCallSendMsgSize(math.MaxInt32)))
+	conn, err := m.cfg.Pool.Get(ctx, host)
 	if err != nil {
-		return errors.Wrap(err, "failed to dial")
+		return errors.Wrap(err, "failed to get conn")
 	}
 
-	m.client = proto.NewServerProtoClient(m.conn)
+	m.client = proto.NewServerProtoClient(conn)
 
 	return nil
 }
-
-func (m *mainter) deinitConn(_ context.Context) error {
-	return m.conn.Close()
-}
-
-func (m *mainter) setTimeout(timeout string) time.Duration {
-	duration, _ := time.ParseDuration(timeout)
-
-	return duration
-}
@@ -0,0 +1,155 @@
+package runner
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/pipego/cli/dag"
+)
+
+// Expand replaces each task of matrix by one task per combination of its values, which is named
+// after the task and the values in the order of keys, e.g. build-1.23-alpine, and has the values
+// set in params. Depends on the task are replaced by depends on all of its combinations, and so are
+// its names in success() and failure() of when. The names are required to be unique among all tasks.
+func Expand(data *Proto) error {
+	var tasks []Task
+	var sources []int
+
+	expanded := map[string][]string{}
+	names := map[string]bool{}
+
+	for i := range data.Spec.Tasks {
+		if len(data.Spec.Tasks[i].Matrix) == 0 {
+			names[data.Spec.Tasks[i].Name] = true
+		}
+	}
+
+	for i := range data.Spec.Tasks {
+		task := &data.Spec.Tasks[i]
+		if len(task.Matrix) == 0 {
+			tasks = append(tasks, *task)
+			sources = append(sources, i)
+			continue
+		}
+		buf, err := expand(task)
+		if err != nil {
+			return errors.Wrap(err, fmt.Sprintf("spec.tasks[%d].matrix", i))
+		}
+		for j := range buf {
+			if names[buf[j].Name] {
+				return errors.New(fmt.Sprintf("spec.tasks[%d].matrix: duplicate task %q", i, buf[j].Name))
+			}
+			names[buf[j].Name] = true
+			expanded[task.Name] = append(expanded[task.Name], buf[j].Name)
+		}
+		tasks = append(tasks, buf...)
+		for range buf {
+			sources = append(sources, i)
+		}
+	}
+
+	if len(expanded) == 0 {
+		return nil
+	}
+
+	for i := range tasks {
+		var depends []string
+		for _, dep := range tasks[i].Depends {
+			if names, ok := expanded[dep]; ok {
+				depends = append(depends, names...)
+			} else {
+				depends = append(depends, dep)
+			}
+		}
+		tasks[i].Depends = depends
+		if tasks[i].When == "" {
+			continue
+		}
+		when, err := expandWhen(tasks[i].When, expanded)
+		if err != nil {
+			return errors.Wrap(err, fmt.Sprintf("spec.tasks[%d].when", sources[i]))
+		}
+		tasks[i].When = when
+	}
+
+	data.Spec.Tasks = tasks
+
+	return nil
+}
+
+func expand(task *Task) ([]Task, error) {
+	keys := sortedKeys(task.Matrix)
+
+	for _, key := range keys {
+		if len(task.Matrix[key]) == 0 {
+			return nil, errors.New(key + ": no values")
+		}
+	}
+
+	combos := [][]string{{}}
+
+	for _, key := range keys {
+		var buf [][]string
+		for _, combo := range combos {
+			for _, value := range task.Matrix[key] {
+				buf = append(buf, append(append([]string{}, combo...), value))
+			}
+		}
+		combos = buf
+	}
+
+	tasks := make([]Task, 0, len(combos))
+
+	for _, combo := range combos {
+		t := *task
+		t.Name = strings.Join(append([]string{task.Name}, combo...), "-")
+		t.Matrix = nil
+		t.Params = append([]TaskParam{}, task.Params...)
+		for i, key := range keys {
+			t.Params = setParam(t.Params, key, combo[i])
+		}
+		tasks = append(tasks, t)
+	}
+
+	return tasks, nil
+}
+
+func expandWhen(expr string, expanded map[string][]string) (string, error) {
+	w, err := dag.ParseWhen(expr)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to parse")
+	}
+
+	for _, item := range w.Tasks() {
+		if _, ok := expanded[item]; ok {
+			return w.Expand(expanded)
+		}
+	}
+
+	return expr, nil
+}
+
+func sortedKeys(m TaskMatrix) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+func setParam(params []TaskParam, name, value string) []TaskParam {
+	for i := range params {
+		if params[i].Name == name {
+			params[i].Value = value
+			return params
+		}
+	}
+
+	return append(params, TaskParam{Name: name, Value: value})
+}
@@ -0,0 +1,81 @@
+package runner
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpand(t *testing.T) {
+	data := Proto{
+		Spec: Spec{
+			Tasks: []Task{
+				{Name: "task1"},
+				{
+					Name:    "task2",
+					Params:  []TaskParam{{Name: "GO", Value: "1.21"}, {Name: "CGO_ENABLED", Value: "0"}},
+					Matrix:  TaskMatrix{"OS": {"alpine", "debian"}, "GO": {"1.22", "1.23"}},
+					Depends: []string{"task1"},
+				},
+				{Name: "task3", Depends: []string{"task1", "task2"}},
+			},
+		},
+	}
+
+	err := Expand(&data)
+	assert.Equal(t, nil, err)
+
+	var names []string
+	for i := range data.Spec.Tasks {
+		names = append(names, data.Spec.Tasks[i].Name)
+	}
+
+	expanded := []string{"task2-1.22-alpine", "task2-1.22-debian", "task2-1.23-alpine", "task2-1.23-debian"}
+
+	assert.Equal(t, append(append([]string{"task1"}, expanded...), "task3"), names)
+	assert.Equal(t, []TaskParam{{Name: "GO", Value: "1.23"}, {Name: "CGO_ENABLED", Value: "0"}, {Name: "OS", Value: "alpine"}},
+		data.Spec.Tasks[3].Params)
+	assert.Equal(t, []string{"task1"}, data.Spec.Tasks[3].Depends)
+	assert.Equal(t, 0, len(data.Spec.Tasks[3].Matrix))
+	assert.Equal(t, append([]string{"task1"}, expanded...), data.Spec.Tasks[5].Depends)
+
+	data.Spec.Tasks = []Task{
+		{Name: "task1", Matrix: TaskMatrix{"GO": {"1.22", "1.23"}}},
+		{Name: "task2", Depends: []string{"task1"}, When: "failure('task1')"},
+		{Name: "task3", Depends: []string{"task2"}, When: "success('task2')"},
+	}
+
+	err = Expand(&data)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "failure('task1-1.22', 'task1-1.23')", data.Spec.Tasks[2].When)
+	assert.Equal(t, "success('task2')", data.Spec.Tasks[3].When)
+
+	data.Spec.Tasks = []Task{
+		{Name: "task1", Matrix: TaskMatrix{"GO": {"1.22", "1.23"}}},
+		{Name: "task2", Depends: []string{"task1"}, When: "tasks.task1.status == 'Succeeded'"},
+	}
+
+	err = Expand(&data)
+	assert.NotEqual(t, nil, err)
+	assert.Contains(t, err.Error(), "spec.tasks[1].when")
+
+	data.Spec.Tasks = []Task{
+		{Name: "task1-1.23"},
+		{Name: "task1", Matrix: TaskMatrix{"GO": {"1.22", "1.23"}}},
+	}
+
+	err = Expand(&data)
+	assert.NotEqual(t, nil, err)
+	assert.Equal(t, `spec.tasks[1].matrix: duplicate task "task1-1.23"`, err.Error())
+
+	data.Spec.Tasks = []Task{{Name: "task1", Matrix: TaskMatrix{"GO": {"1.23", "1.23"}}}}
+
+	err = Expand(&data)
+	assert.NotEqual(t, nil, err)
+	assert.Equal(t, `spec.tasks[0].matrix: duplicate task "task1-1.23"`, err.Error())
+
+	data.Spec.Tasks = []Task{{Name: "task1", Matrix: TaskMatrix{"GO": {}}}}
+
+	err = Expand(&data)
+	assert.NotEqual(t, nil, err)
+}
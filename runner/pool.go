@@ -0,0 +1,140 @@
+package runner
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/status"
+
+	"github.com/pipego/cli/config"
+	proto "github.com/pipego/cli/runner/proto"
+)
+
+type Pool interface {
+	Init(context.Context) error
+	Deinit(context.Context) error
+	Get(context.Context, string) (*grpc.ClientConn, error)
+}
+
+type PoolConfig struct {
+	Config    config.Config
+	Keepalive keepalive.ClientParameters
+}
+
+type pool struct {
+	cfg   *PoolConfig
+	conns map[string]*grpc.ClientConn
+	mutex sync.Mutex
+}
+
+func PoolNew(_ context.Context, cfg *PoolConfig) Pool {
+	return &pool{
+		cfg:   cfg,
+		conns: map[string]*grpc.ClientConn{},
+	}
+}
+
+func PoolDefaultConfig() *PoolConfig {
+	return &PoolConfig{
+		// Pings more often than every 5 minutes are rejected by grpc servers by default.
+		Keepalive: keepalive.ClientParameters{
+			Time:    5 * time.Minute,
+			Timeout: 20 * time.Second,
+		},
+	}
+}
+
+func (p *pool) Init(_ context.Context) error {
+	return nil
+}
+
+func (p *pool) Deinit(_ context.Context) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	for host, conn := range p.conns {
+		_ = conn.Close()
+		delete(p.conns, host)
+	}
+
+	return nil
+}
+
+// Get returns the shared connection to host (host:port), dialing it if absent
+// or shut down, once it is connected and healthy within the dial timeout.
+func (p *pool) Get(ctx context.Context, host string) (*grpc.ClientConn, error) {
+	timeout, err := p.cfg.Config.Spec.Runner.DialTimeout()
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid timeout")
+	}
+
+	conn, err := p.conn(host)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to dial")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if err := p.check(ctx, conn); err != nil {
+		return nil, errors.Wrap(err, "failed to check "+host)
+	}
+
+	return conn, nil
+}
+
+func (p *pool) conn(host string) (*grpc.ClientConn, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if conn, ok := p.conns[host]; ok {
+		if conn.GetState() != connectivity.Shutdown {
+			return conn, nil
+		}
+		delete(p.conns, host)
+	}
+
+	// Tasks are not retried as they are not idempotent.
+	opts, err := p.cfg.Config.Spec.Runner.DialOptions(
+		proto.ServerProto_SendGlance_FullMethodName,
+		proto.ServerProto_SendMaint_FullMethodName,
+		proto.ServerProto_SendConfig_FullMethodName)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to init options")
+	}
+
+	conn, err := grpc.Dial(host, append(opts, grpc.WithKeepaliveParams(p.cfg.Keepalive))...)
+	if err != nil {
+		return nil, err
+	}
+
+	p.conns[host] = conn
+
+	return conn, nil
+}
+
+// check waits until conn is ready and then checks the health service of the
+// runner, which is considered healthy if the service is unimplemented.
+func (p *pool) check(ctx context.Context, conn *grpc.ClientConn) error {
+	res, err := grpc_health_v1.NewHealthClient(conn).Check(ctx, &grpc_health_v1.HealthCheckRequest{},
+		grpc.WaitForReady(true))
+	if err != nil {
+		if status.Code(err) == codes.Unimplemented {
+			return nil
+		}
+		return err
+	}
+
+	if res.GetStatus() != grpc_health_v1.HealthCheckResponse_SERVING {
+		return errors.New("invalid status " + res.GetStatus().String())
+	}
+
+	return nil
+}
@@ -0,0 +1,104 @@
+package runner
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestPool(t *testing.T) {
+	ctx := context.Background()
+
+	p := PoolNew(ctx, PoolDefaultConfig())
+	assert.Equal(t, nil, p.Init(ctx))
+
+	srv := startRunner(t, &runnerTest{})
+	host := net.JoinHostPort(srv.Host, strconv.Itoa(srv.Port))
+
+	conn1, err := p.Get(ctx, host)
+	assert.Equal(t, nil, err)
+
+	conn2, err := p.Get(ctx, host)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, conn1, conn2)
+
+	assert.Equal(t, nil, p.Deinit(ctx))
+
+	conn3, err := p.Get(ctx, host)
+	assert.Equal(t, nil, err)
+	assert.NotEqual(t, conn1, conn3)
+
+	_ = p.Deinit(ctx)
+}
+
+func TestPoolUnreachable(t *testing.T) {
+	ctx := context.Background()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Equal(t, nil, err)
+
+	host := lis.Addr().String()
+	_ = lis.Close()
+
+	c := PoolDefaultConfig()
+	c.Config.Spec.Runner.Timeout = "200ms"
+
+	p := PoolNew(ctx, c)
+
+	defer func() {
+		_ = p.Deinit(ctx)
+	}()
+
+	start := time.Now()
+
+	_, err = p.Get(ctx, host)
+	assert.NotEqual(t, nil, err)
+	assert.Contains(t, err.Error(), host)
+	assert.Less(t, time.Since(start), 2*time.Second)
+}
+
+func TestPoolHealth(t *testing.T) {
+	ctx := context.Background()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Equal(t, nil, err)
+
+	h := health.NewServer()
+	h.SetServingStatus("", grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+
+	s := grpc.NewServer()
+	grpc_health_v1.RegisterHealthServer(s, h)
+
+	go func() {
+		_ = s.Serve(lis)
+	}()
+
+	defer s.Stop()
+
+	p := PoolNew(ctx, PoolDefaultConfig())
+
+	defer func() {
+		_ = p.Deinit(ctx)
+	}()
+
+	c, cancel := context.WithTimeout(ctx, 500*time.Millisecond)
+	defer cancel()
+
+	_, err = p.Get(c, lis.Addr().String())
+	assert.NotEqual(t, nil, err)
+
+	h.SetServingStatus("", grpc_health_v1.HealthCheckResponse_SERVING)
+
+	c, cancel = context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	_, err = p.Get(c, lis.Addr().String())
+	assert.Equal(t, nil, err)
+}
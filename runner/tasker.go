@@ -1,20 +1,23 @@
 package runner
 
 import (
-	"bytes"
-	"compress/gzip"
 	"context"
-	"math"
+	"io"
+	"net"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/pkg/errors"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"github.com/pipego/cli/config"
 	"github.com/pipego/cli/dag"
 	proto "github.com/pipego/cli/runner/proto"
+	"github.com/pipego/cli/scheduler"
 	_runner "github.com/pipego/dag/runner"
 )
 
@@ -24,30 +27,59 @@ const (
 	Unit  = "hour"
 )
 
+const (
+	StatusPending   = "pending"
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+	StatusSkipped   = "skipped"
+	StatusCanceled  = "canceled"
+)
+
+// OutputPrefix starts the log lines of tasks setting an output, e.g. ::set-output name=VERSION::1.0
+const (
+	OutputPrefix = "::set-output name="
+)
+
+const (
+	RetryOnAny     = "any"
+	RetryOnError   = "error"
+	RetryOnTimeout = "timeout"
+)
+
 type Tasker interface {
 	Init(context.Context) error
 	Deinit(context.Context) error
 	Run(context.Context) error
-	Tail(ctx context.Context) _runner.Log
+	Tail(ctx context.Context) <-chan *TaskLine
 	Tasks(ctx context.Context) []Task
+	Status(ctx context.Context) []TaskStatus
+	Plan(ctx context.Context) ([]TaskPlan, error)
 }
 
 type TaskerConfig struct {
-	Config config.Config
-	Dag    dag.DAG
-	Data   Proto
+	Config    config.Config
+	Dag       dag.DAG
+	Data      Proto
+	Pool      Pool
+	Resume    []TaskStatus
+	Scheduler scheduler.Scheduler
 }
 
 type tasker struct {
-	cfg    *TaskerConfig
-	client proto.ServerProtoClient
-	conn   *grpc.ClientConn
-	log    _runner.Log
+	cfg      *TaskerConfig
+	ctx      context.Context
+	log      chan *TaskLine
+	selected map[string]bool
+	status   map[string]TaskStatus
+	lock     sync.RWMutex
 }
 
 func TaskerNew(_ context.Context, cfg *TaskerConfig) Tasker {
 	return &tasker{
-		cfg: cfg,
+		cfg:    cfg,
+		ctx:    context.Background(),
+		status: map[string]TaskStatus{},
 	}
 }
 
@@ -56,10 +88,6 @@ func TaskerDefaultConfig() *TaskerConfig {
 }
 
 func (t *tasker) Init(ctx context.Context) error {
-	if err := t.initConn(ctx); err != nil {
-		return errors.Wrap(err, "failed to init conn")
-	}
-
 	if err := t.initDag(ctx); err != nil {
 		return errors.Wrap(err, "failed to init dag")
 	}
@@ -69,16 +97,49 @@ func (t *tasker) Init(ctx context.Context) error {
 
 func (t *tasker) Deinit(ctx context.Context) error {
 	_ = t.deinitDag(ctx)
-	_ = t.deinitConn(ctx)
 
 	return nil
 }
 
 func (t *tasker) Run(ctx context.Context) error {
-	return t.runDag(ctx)
+	timeout, err := config.ParseDuration(t.cfg.Data.Spec.Timeout, 0)
+	if err != nil {
+		return errors.Wrap(err, "invalid spec.timeout")
+	}
+
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	// The routine of dag has no context, hence it is kept here for tasks.
+	t.ctx = ctx
+
+	if err := t.runDag(ctx); err != nil {
+		return err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return errors.Wrap(err, "interrupted")
+	}
+
+	var failed []string
+
+	for _, item := range t.Status(ctx) {
+		if item.Status == StatusFailed {
+			failed = append(failed, item.Name)
+		}
+	}
+
+	if len(failed) != 0 {
+		return errors.New("failed tasks: " + strings.Join(failed, ", "))
+	}
+
+	return nil
 }
 
-func (t *tasker) Tail(ctx context.Context) _runner.Log {
+func (t *tasker) Tail(_ context.Context) <-chan *TaskLine {
 	return t.log
 }
 
@@ -86,27 +147,68 @@ func (t *tasker) Tasks(_ context.Context) []Task {
 	return t.cfg.Data.Spec.Tasks
 }
 
-func (t *tasker) initConn(_ context.Context) error {
-	var err error
+func (t *tasker) Status(_ context.Context) []TaskStatus {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
 
-	host := t.cfg.Config.Spec.Runner.Host
-	port := t.cfg.Config.Spec.Runner.Port
+	var buf []TaskStatus
 
-	t.conn, err = grpc.Dial(host+":"+strconv.Itoa(port),
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
-		grpc.WithBlock(),
-		grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(math.MaxInt32), grpc.MaxCallSendMsgSize(math.MaxInt32)))
-	if err != nil {
-		return errors.Wrap(err, "failed to dial")
+	for i := range t.cfg.Data.Spec.Tasks {
+		name := t.cfg.Data.Spec.Tasks[i].Name
+		if t.selected != nil && !t.selected[name] {
+			continue
+		}
+		if s, ok := t.status[name]; ok {
+			buf = append(buf, s)
+		} else {
+			buf = append(buf, TaskStatus{Name: name, Status: StatusPending})
+		}
 	}
 
-	t.client = proto.NewServerProtoClient(t.conn)
+	return buf
+}
 
-	return nil
+func (t *tasker) initConn(ctx context.Context, host string) (proto.ServerProtoClient, error) {
+	conn, err := t.cfg.Pool.Get(ctx, host)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get conn")
+	}
+
+	return proto.NewServerProtoClient(conn), nil
 }
 
-func (t *tasker) deinitConn(_ context.Context) error {
-	return t.conn.Close()
+// Plan returns the tasks in the order of dag levels with the hosts chosen by
+// the scheduler, without sending them. Tasks failed to schedule keep the error.
+func (t *tasker) Plan(ctx context.Context) ([]TaskPlan, error) {
+	levels, err := t.cfg.Dag.Levels(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to level dag")
+	}
+
+	var buf []TaskPlan
+
+	for i, level := range levels {
+		for _, name := range level {
+			task := t.task(name)
+			p := TaskPlan{
+				Level:    i + 1,
+				Name:     name,
+				Depends:  task.Depends,
+				When:     task.When,
+				Language: task.Language.Name,
+				Image:    task.Language.Artifact.Image,
+				Params:   task.Params,
+				Commands: task.Commands,
+				Timeout:  task.Timeout,
+			}
+			if p.Host, err = t.schedule(ctx, &task); err != nil {
+				p.Error = err.Error()
+			}
+			buf = append(buf, p)
+		}
+	}
+
+	return buf, nil
 }
 
 func (t *tasker) initDag(ctx context.Context) error {
@@ -138,36 +240,135 @@ func (t *tasker) initDag(ctx context.Context) error {
 	for i := range t.cfg.Data.Spec.Tasks {
 		tasks = append(tasks, dag.Task{
 			Name:     t.cfg.Data.Spec.Tasks[i].Name,
-			File:     _runner.File(t.cfg.Data.Spec.Tasks[i].File),
+			File:     _runner.File{Content: t.cfg.Data.Spec.Tasks[i].File.Content, Gzip: t.cfg.Data.Spec.Tasks[i].File.Gzip},
 			Params:   params(t.cfg.Data.Spec.Tasks[i].Params),
 			Commands: t.cfg.Data.Spec.Tasks[i].Commands,
 			Width:    t.cfg.Data.Spec.Tasks[i].Log.Width,
 			Language: language(t.cfg.Data.Spec.Tasks[i].Language),
 			Depends:  t.cfg.Data.Spec.Tasks[i].Depends,
+			Tags:     t.cfg.Data.Spec.Tasks[i].Tags,
 		})
 	}
 
-	t.log = _runner.Log{
-		Line: make(chan *_runner.Line, Count),
+	t.log = make(chan *TaskLine, Count)
+
+	if err := t.cfg.Dag.Init(ctx, tasks); err != nil {
+		return err
+	}
+
+	// Tasks filtered out of the dag are neither run nor reported.
+	t.selected = map[string]bool{}
+	for _, item := range t.cfg.Dag.Graph(ctx).Vertex {
+		t.selected[item.Name] = true
 	}
 
-	return t.cfg.Dag.Init(ctx, tasks)
+	return nil
 }
 
 func (t *tasker) deinitDag(ctx context.Context) error {
 	_ = t.cfg.Dag.Deinit(ctx)
 
-	close(t.log.Line)
+	close(t.log)
 
 	return nil
 }
 
 func (t *tasker) runDag(ctx context.Context) error {
-	return t.cfg.Dag.Run(ctx, t.routine, t.log)
+	// Lines are sent to t.log tagged with the task name, instead of the untagged log of dag.
+	return t.cfg.Dag.Run(ctx, t.routine, _runner.Log{})
 }
 
 func (t *tasker) routine(name string, file _runner.File, envs []_runner.Param, args []string, width int64,
-	lang _runner.Language, log _runner.Log) error {
+	lang _runner.Language, _ _runner.Log) error {
+	// Failures are recorded rather than returned so that the dag keeps on
+	// running independent branches, and dependents are skipped here instead.
+	if err := t.ctx.Err(); err != nil {
+		t.setStatus(name, StatusCanceled, err.Error())
+		return nil
+	}
+
+	if s, ok := t.resumed(name); ok {
+		t.updateStatus(name, func(status *TaskStatus) {
+			*status = s
+			status.Resumed = true
+		})
+		return nil
+	}
+
+	task := t.task(name)
+
+	if task.When != "" {
+		ok, err := t.when(&task)
+		if err != nil {
+			t.setStatus(name, StatusFailed, "invalid when: "+err.Error())
+			return nil
+		}
+		if !ok {
+			t.setStatus(name, StatusSkipped, "when "+task.When+" not met")
+			return nil
+		}
+	} else if dep := t.unsucceeded(t.depends(&task)); dep != "" {
+		t.setStatus(name, StatusSkipped, "depend "+dep+" not succeeded")
+		return nil
+	}
+
+	t.setStatus(name, StatusRunning, "")
+
+	envs = t.inject(&task, envs)
+
+	delay, err := config.ParseDuration(task.RetryDelay, 0)
+	if err != nil {
+		t.setStatus(name, StatusFailed, "invalid retryDelay: "+err.Error())
+		return nil
+	}
+
+	// Attempts are retried here before the dag sees the task as done, with the delay doubled each time.
+	for attempt := 1; ; attempt++ {
+		t.setAttempts(name, attempt)
+		err = t.send(&task, attempt, file, envs, args, width, lang)
+		if err == nil {
+			break
+		}
+		if t.ctx.Err() != nil {
+			t.setStatus(name, StatusCanceled, err.Error())
+			return nil
+		}
+		if attempt > task.Retries || !retryable(task.RetryOn, err) {
+			t.setStatus(name, StatusFailed, err.Error())
+			return nil
+		}
+		select {
+		case <-t.ctx.Done():
+			t.setStatus(name, StatusCanceled, t.ctx.Err().Error())
+			return nil
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+
+	t.setStatus(name, StatusSucceeded, "")
+
+	return nil
+}
+
+func retryable(on string, err error) bool {
+	timeout := errors.Is(err, context.DeadlineExceeded) || status.Code(err) == codes.DeadlineExceeded
+
+	switch on {
+	case RetryOnTimeout:
+		return timeout
+	case RetryOnError:
+		return !timeout
+	}
+
+	return true
+}
+
+// nolint: funlen
+func (t *tasker) send(task *Task, attempt int, file _runner.File, envs []_runner.Param, args []string, width int64,
+	lang _runner.Language) error {
+	name := task.Name
+
 	params := func(p []_runner.Param) []*proto.TaskParam {
 		var buf []*proto.TaskParam
 		for _, item := range p {
@@ -191,12 +392,20 @@ func (t *tasker) routine(name string, file _runner.File, envs []_runner.Param, a
 		}
 	}
 
-	task := func() *proto.Task {
+	c := codec(&task.File, len(file.Content))
+
+	content, err := compress([]byte(file.Content), c)
+	if err != nil {
+		return errors.Wrap(err, "failed to compress")
+	}
+
+	_task := func() *proto.Task {
 		return &proto.Task{
 			Name: name,
 			File: &proto.TaskFile{
-				Content: t.contentHelper([]byte(file.Content), file.Gzip),
-				Gzip:    file.Gzip,
+				Content: content,
+				Gzip:    c == CompressGzip,
+				Zstd:    c == CompressZstd,
 			},
 			Params:   params(envs),
 			Commands: args,
@@ -207,43 +416,73 @@ func (t *tasker) routine(name string, file _runner.File, envs []_runner.Param, a
 		}
 	}()
 
-	output := func(s proto.ServerProto_SendTaskClient) {
-		done := make(chan bool)
-		go func(s proto.ServerProto_SendTaskClient, log _runner.Log, done chan bool) {
-			for {
-				if recv, err := s.Recv(); err == nil {
-					log.Line <- &_runner.Line{
-						Pos:     recv.GetOutput().GetPos(),
-						Time:    recv.GetOutput().GetTime(),
-						Message: recv.GetOutput().GetMessage(),
-					}
-					if recv.GetOutput().GetMessage() == "EOF" {
-						_ = s.CloseSend()
-						done <- true
-						return
-					}
-				} else {
-					_ = s.CloseSend()
-					done <- false
-					return
+	output := func(s proto.ServerProto_SendTaskClient) error {
+		var e error
+		for {
+			recv, err := s.Recv()
+			if err != nil {
+				if errors.Is(err, io.EOF) {
+					return e
 				}
+				return errors.Wrap(err, "failed to recv")
+			}
+			if recv.GetError() != "" && e == nil {
+				e = errors.New(recv.GetError())
+			}
+			if recv.GetOutput() == nil {
+				continue
+			}
+			if key, val, ok := parseOutput(recv.GetOutput().GetMessage()); ok && contains(task.Outputs, key) {
+				t.setOutput(name, key, val)
+			}
+			t.appendLog(name, TaskOutput{
+				Attempt: attempt,
+				Pos:     recv.GetOutput().GetPos(),
+				Time:    recv.GetOutput().GetTime(),
+				Message: recv.GetOutput().GetMessage(),
+			})
+			t.log <- &TaskLine{
+				Name:    name,
+				Attempt: attempt,
+				Pos:     recv.GetOutput().GetPos(),
+				Time:    recv.GetOutput().GetTime(),
+				Message: recv.GetOutput().GetMessage(),
 			}
-		}(s, log, done)
-		<-done
+			if recv.GetOutput().GetMessage() == "EOF" {
+				return e
+			}
+		}
+	}
+
+	timeout, err := config.ParseTimeout(task.Timeout, TaskTimeout)
+	if err != nil {
+		return errors.Wrap(err, "invalid timeout")
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), t.setTimeout(name))
+	ctx, cancel := context.WithTimeout(t.ctx, timeout)
 	defer cancel()
 
-	reply, err := t.client.SendTask(ctx)
-	defer func() {
-		_ = reply.CloseSend()
-	}()
+	host, err := t.schedule(ctx, task)
+	if err != nil {
+		return errors.Wrap(err, "failed to schedule")
+	}
 
+	t.setHost(name, host)
+
+	client, err := t.initConn(ctx, host)
+	if err != nil {
+		return errors.Wrap(err, "failed to init conn")
+	}
+
+	reply, err := client.SendTask(ctx)
 	if err != nil {
 		return errors.Wrap(err, "failed to set")
 	}
 
+	defer func() {
+		_ = reply.CloseSend()
+	}()
+
 	if err := reply.Send(&proto.TaskRequest{
 		ApiVersion: t.cfg.Data.ApiVersion,
 		Kind:       t.cfg.Data.Kind,
@@ -251,51 +490,264 @@ func (t *tasker) routine(name string, file _runner.File, envs []_runner.Param, a
 			Name: t.cfg.Data.Metadata.Name,
 		},
 		Spec: &proto.TaskSpec{
-			Task: task,
+			Task: _task,
 		},
 	}); err != nil {
 		return errors.Wrap(err, "failed to send")
 	}
 
-	output(reply)
+	return output(reply)
+}
 
-	return nil
+func (t *tasker) schedule(ctx context.Context, task *Task) (string, error) {
+	port := strconv.Itoa(t.cfg.Config.Spec.Runner.Port)
+
+	if t.cfg.Scheduler == nil {
+		return net.JoinHostPort(t.cfg.Config.Spec.Runner.Host, port), nil
+	}
+
+	res, err := t.cfg.Scheduler.Run(ctx, scheduler.Task{
+		Name:          task.Name,
+		NodeName:      task.NodeName,
+		NodeSelectors: task.NodeSelectors,
+		RequestedResource: scheduler.Resource{
+			MilliCPU: task.RequestedResource.MilliCPU,
+			Memory:   task.RequestedResource.Memory,
+			Storage:  task.RequestedResource.Storage,
+		},
+		ToleratesUnschedulable: task.ToleratesUnschedulable,
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "failed to run")
+	}
+
+	if res.Error != "" {
+		return "", errors.New(res.Error)
+	}
+
+	if res.Host == "" {
+		return "", errors.New("invalid host of node " + res.Name)
+	}
+
+	if _, _, err := net.SplitHostPort(res.Host); err == nil {
+		return res.Host, nil
+	}
+
+	return net.JoinHostPort(res.Host, port), nil
+}
+
+func (t *tasker) setStatus(name, status, reason string) {
+	t.updateStatus(name, func(s *TaskStatus) {
+		now := time.Now()
+		s.Status = status
+		s.Error = reason
+		if s.Start.IsZero() {
+			s.Start = now
+		}
+		if status != StatusRunning {
+			s.End = now
+			s.Duration = s.End.Sub(s.Start).String()
+		}
+	})
+}
+
+func (t *tasker) setHost(name, host string) {
+	t.updateStatus(name, func(s *TaskStatus) {
+		s.Host = host
+	})
+}
+
+func (t *tasker) setAttempts(name string, attempts int) {
+	t.updateStatus(name, func(s *TaskStatus) {
+		s.Attempts = attempts
+	})
+}
+
+func (t *tasker) appendLog(name string, output TaskOutput) {
+	t.updateStatus(name, func(s *TaskStatus) {
+		s.Log = append(s.Log, output)
+	})
+}
+
+func (t *tasker) setOutput(name, key, val string) {
+	t.updateStatus(name, func(s *TaskStatus) {
+		if s.Outputs == nil {
+			s.Outputs = map[string]string{}
+		}
+		s.Outputs[key] = val
+	})
+}
+
+func (t *tasker) updateStatus(name string, update func(*TaskStatus)) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	s := t.status[name]
+	s.Name = name
+	update(&s)
+	t.status[name] = s
 }
 
-func (t *tasker) contentHelper(data []byte, compressed bool) []byte {
-	var b bytes.Buffer
+// depends returns the depends of task in the dag, where each depend filtered out is replaced
+// by its nearest depends in the dag, as the dag does.
+func (t *tasker) depends(task *Task) []string {
+	var buf []string
+
+	visited := map[string]bool{}
+
+	var visit func(string)
 
-	if !compressed {
-		return data
+	visit = func(name string) {
+		if visited[name] {
+			return
+		}
+		visited[name] = true
+		if t.selected[name] {
+			buf = append(buf, name)
+			return
+		}
+		for _, item := range t.task(name).Depends {
+			visit(item)
+		}
 	}
 
-	gz := gzip.NewWriter(&b)
-	defer func(gz *gzip.Writer) {
-		_ = gz.Close()
-	}(gz)
+	for _, item := range task.Depends {
+		visit(item)
+	}
+
+	return buf
+}
+
+func (t *tasker) unsucceeded(depends []string) string {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	for _, item := range depends {
+		if s, ok := t.status[item]; ok && s.Status != StatusSucceeded {
+			return item
+		}
+	}
+
+	return ""
+}
+
+// when evaluates the condition of task against its params and the status of tasks finished.
+func (t *tasker) when(task *Task) (bool, error) {
+	w, err := dag.ParseWhen(task.When)
+	if err != nil {
+		return false, err
+	}
+
+	env := dag.Env{
+		Params:  map[string]string{},
+		Depends: t.depends(task),
+		Status:  map[string]string{},
+		Outputs: map[string]map[string]string{},
+		Graph:   map[string][]string{},
+	}
+
+	for _, item := range task.Params {
+		env.Params[item.Name] = item.Value
+	}
+
+	t.lock.RLock()
+	for name, item := range t.status {
+		env.Status[name] = item.Status
+		env.Outputs[name] = item.Outputs
+		buf := t.task(name)
+		env.Graph[name] = t.depends(&buf)
+	}
+	t.lock.RUnlock()
+
+	return w.Eval(&env)
+}
+
+// inject returns envs with the outputs of the tasks task depends on, directly or not, set as params,
+// where the outputs of the nearer tasks take precedence.
+func (t *tasker) inject(task *Task, envs []_runner.Param) []_runner.Param {
+	var order []string
+
+	visited := map[string]bool{}
+
+	var visit func(string)
+
+	visit = func(name string) {
+		if visited[name] {
+			return
+		}
+		visited[name] = true
+		for _, item := range t.task(name).Depends {
+			visit(item)
+		}
+		order = append(order, name)
+	}
+
+	for _, item := range task.Depends {
+		visit(item)
+	}
+
+	buf := append([]_runner.Param{}, envs...)
+
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	for _, name := range order {
+		outputs := t.status[name].Outputs
+		keys := make([]string, 0, len(outputs))
+		for key := range outputs {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			buf = setEnv(buf, key, outputs[key])
+		}
+	}
+
+	return buf
+}
+
+func setEnv(envs []_runner.Param, name, value string) []_runner.Param {
+	for i := range envs {
+		if envs[i].Name == name {
+			envs[i].Value = value
+			return envs
+		}
+	}
+
+	return append(envs, _runner.Param{Name: name, Value: value})
+}
 
-	if _, err := gz.Write(data); err != nil {
-		return data
+// parseOutput returns the key and value of an output set by message.
+func parseOutput(message string) (key, val string, ok bool) {
+	if !strings.HasPrefix(message, OutputPrefix) {
+		return "", "", false
 	}
 
-	if err := gz.Flush(); err != nil {
-		return data
+	key, val, ok = strings.Cut(strings.TrimPrefix(message, OutputPrefix), "::")
+	if !ok || key == "" {
+		return "", "", false
 	}
 
-	return b.Bytes()
+	return key, val, true
 }
 
-func (t *tasker) setTimeout(name string) time.Duration {
-	var _t Task
+// resumed returns the status of name if succeeded in the run resumed.
+func (t *tasker) resumed(name string) (TaskStatus, bool) {
+	for i := range t.cfg.Resume {
+		if t.cfg.Resume[i].Name == name && t.cfg.Resume[i].Status == StatusSucceeded {
+			return t.cfg.Resume[i], true
+		}
+	}
+
+	return TaskStatus{}, false
+}
 
+func (t *tasker) task(name string) Task {
 	for i := range t.cfg.Data.Spec.Tasks {
 		if name == t.cfg.Data.Spec.Tasks[i].Name {
-			_t = t.cfg.Data.Spec.Tasks[i]
-			break
+			return t.cfg.Data.Spec.Tasks[i]
 		}
 	}
 
-	duration, _ := time.ParseDuration(_t.Timeout)
-
-	return duration
+	return Task{Name: name}
 }
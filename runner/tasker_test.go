@@ -2,12 +2,507 @@ package runner
 
 import (
 	"context"
+	"net"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/pipego/cli/config"
+	"github.com/pipego/cli/dag"
+	proto "github.com/pipego/cli/runner/proto"
+	"github.com/pipego/cli/scheduler"
 )
 
+type runnerTest struct {
+	proto.UnimplementedServerProtoServer
+	block    map[string]bool
+	canceled chan string
+	fail     map[string]bool
+	flaky    map[string]int
+	lines    map[string][]string
+	params   map[string]map[string]string
+	files    map[string]string
+	mutex    sync.Mutex
+}
+
+func (r *runnerTest) SendTask(srv proto.ServerProto_SendTaskServer) error {
+	req, err := srv.Recv()
+	if err != nil {
+		return err
+	}
+
+	if name := req.GetSpec().GetTask().GetName(); r.block[name] {
+		<-srv.Context().Done()
+		r.canceled <- name
+		return srv.Context().Err()
+	}
+
+	if r.fail[req.GetSpec().GetTask().GetName()] || r.flake(req.GetSpec().GetTask().GetName()) {
+		_ = srv.Send(&proto.TaskReply{Error: "exit status 1"})
+	} else {
+		r.record(req.GetSpec().GetTask())
+		for _, item := range r.lines[req.GetSpec().GetTask().GetName()] {
+			_ = srv.Send(&proto.TaskReply{Output: &proto.TaskOutput{Pos: 1, Message: item}})
+		}
+		_ = srv.Send(&proto.TaskReply{Output: &proto.TaskOutput{Pos: 1, Message: "hello"}})
+	}
+
+	return srv.Send(&proto.TaskReply{Output: &proto.TaskOutput{Pos: 2, Message: "EOF"}})
+}
+
+// record keeps the params of task sent.
+func (r *runnerTest) record(task *proto.Task) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.params == nil {
+		r.params = map[string]map[string]string{}
+	}
+
+	r.params[task.GetName()] = map[string]string{}
+	for _, item := range task.GetParams() {
+		r.params[task.GetName()][item.GetName()] = item.GetValue()
+	}
+
+	if r.files == nil {
+		r.files = map[string]string{}
+	}
+
+	// The runner decompresses file.content as flagged.
+	content, err := decompress(task.GetFile().GetContent(), task.GetFile().GetGzip(), task.GetFile().GetZstd())
+	if err != nil {
+		content = []byte("invalid: " + err.Error())
+	}
+
+	r.files[task.GetName()] = string(content)
+}
+
+// flake reports whether name fails this time, which is true for the first flaky[name] times.
+func (r *runnerTest) flake(name string) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.flaky[name] > 0 {
+		r.flaky[name]--
+		return true
+	}
+
+	return false
+}
+
+func startRunner(t *testing.T, srv proto.ServerProtoServer) config.Server {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Equal(t, nil, err)
+
+	s := grpc.NewServer()
+	proto.RegisterServerProtoServer(s, srv)
+
+	go func() {
+		_ = s.Serve(lis)
+	}()
+
+	t.Cleanup(s.Stop)
+
+	return config.Server{
+		Host: "127.0.0.1",
+		Port: lis.Addr().(*net.TCPAddr).Port,
+	}
+}
+
+// runTasker runs tasks by c, with the dag of c if set, on a runner serving srv, and returns
+// the status of tasks by name with the error of run.
+func runTasker(t *testing.T, c *TaskerConfig, srv proto.ServerProtoServer, tasks []Task) (map[string]TaskStatus, error) {
+	ctx := context.Background()
+
+	c.Config.Spec.Runner = startRunner(t, srv)
+	if c.Dag == nil {
+		c.Dag = dag.New(ctx, dag.DefaultConfig())
+	}
+	c.Pool = PoolNew(ctx, PoolDefaultConfig())
+	c.Data.Spec.Tasks = tasks
+
+	_t := TaskerNew(ctx, c)
+
+	if err := _t.Init(ctx); err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		_ = _t.Deinit(ctx)
+		_ = c.Pool.Deinit(ctx)
+	}()
+
+	err := _t.Run(ctx)
+
+	status := map[string]TaskStatus{}
+	for _, item := range _t.Status(ctx) {
+		status[item.Name] = item
+	}
+
+	// Each line of the log of tasks is tailed too.
+	lines := map[string]int{}
+	for len(_t.Tail(ctx)) != 0 {
+		line := <-_t.Tail(ctx)
+		lines[line.Name]++
+	}
+
+	for name, item := range status {
+		assert.Equal(t, len(item.Log), lines[name], name)
+	}
+
+	return status, err
+}
+
+type schedulerTest struct {
+	req scheduler.Task
+	res scheduler.Result
+}
+
+func (s *schedulerTest) Init(_ context.Context) error {
+	return nil
+}
+
+func (s *schedulerTest) Deinit(_ context.Context) error {
+	return nil
+}
+
+func (s *schedulerTest) Run(_ context.Context, task scheduler.Task) (scheduler.Result, error) {
+	s.req = task
+	return s.res, nil
+}
+
+func (s *schedulerTest) Task(_ context.Context) scheduler.Task {
+	return scheduler.Task{}
+}
+
 func TestTasker(t *testing.T) {
 	_t := TaskerNew(context.Background(), TaskerDefaultConfig())
 	assert.NotEqual(t, nil, _t)
 }
+
+func TestSchedule(t *testing.T) {
+	ctx := context.Background()
+
+	c := TaskerDefaultConfig()
+	c.Config = config.Config{
+		Spec: config.Spec{
+			Runner: config.Server{Host: "127.0.0.1", Port: 29090},
+		},
+	}
+	c.Data.Spec.Tasks = []Task{
+		{
+			Name:          "task1",
+			NodeName:      "node2",
+			NodeSelectors: []string{"ssd"},
+			RequestedResource: TaskResource{
+				MilliCPU: 512,
+				Memory:   1024,
+				Storage:  2048,
+			},
+			ToleratesUnschedulable: true,
+		},
+	}
+
+	_t := TaskerNew(ctx, c).(*tasker)
+	task := _t.task("task1")
+
+	host, err := _t.schedule(ctx, &task)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "127.0.0.1:29090", host)
+
+	s := &schedulerTest{res: scheduler.Result{Name: "node2", Host: "127.0.0.2"}}
+	c.Scheduler = s
+
+	host, err = _t.schedule(ctx, &task)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "127.0.0.2:29090", host)
+	assert.Equal(t, scheduler.Task{
+		Name:          "task1",
+		NodeName:      "node2",
+		NodeSelectors: []string{"ssd"},
+		RequestedResource: scheduler.Resource{
+			MilliCPU: 512,
+			Memory:   1024,
+			Storage:  2048,
+		},
+		ToleratesUnschedulable: true,
+	}, s.req)
+
+	s.res = scheduler.Result{Name: "node2", Host: "127.0.0.2:29091"}
+
+	host, err = _t.schedule(ctx, &task)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "127.0.0.2:29091", host)
+
+	s.res = scheduler.Result{Error: "no node available"}
+
+	_, err = _t.schedule(ctx, &task)
+	assert.NotEqual(t, nil, err)
+}
+
+func TestTaskerRun(t *testing.T) {
+	status, err := runTasker(t, TaskerDefaultConfig(), &runnerTest{fail: map[string]bool{"task1": true}}, []Task{
+		{Name: "task1", Commands: []string{"false"}, Timeout: "10s"},
+		{Name: "task2", Commands: []string{"true"}, Timeout: "10s"},
+		{Name: "task3", Commands: []string{"true"}, Timeout: "10s", Depends: []string{"task1"}},
+		{Name: "task4", Commands: []string{"true"}, Timeout: "10s", Depends: []string{"task2"}},
+		{Name: "task5", Commands: []string{"true"}, Timeout: "10s", Depends: []string{"task3"}},
+	})
+	assert.NotEqual(t, nil, err)
+	assert.Contains(t, err.Error(), "task1")
+
+	assert.Equal(t, map[string]string{
+		"task1": StatusFailed,
+		"task2": StatusSucceeded,
+		"task3": StatusSkipped,
+		"task4": StatusSucceeded,
+		"task5": StatusSkipped,
+	}, statuses(status))
+
+	lines := map[string]int{}
+	for name, item := range status {
+		if len(item.Log) != 0 {
+			lines[name] = len(item.Log)
+		}
+	}
+
+	assert.Equal(t, map[string]int{"task1": 1, "task2": 2, "task4": 2}, lines)
+}
+
+func statuses(status map[string]TaskStatus) map[string]string {
+	buf := map[string]string{}
+	for name, item := range status {
+		buf[name] = item.Status
+	}
+	return buf
+}
+
+func TestTaskerRetry(t *testing.T) {
+	srv := &runnerTest{
+		fail:  map[string]bool{"task3": true},
+		flaky: map[string]int{"task1": 1, "task2": 1},
+	}
+
+	status, err := runTasker(t, TaskerDefaultConfig(), srv, []Task{
+		{Name: "task1", Commands: []string{"true"}, Timeout: "10s", Retries: 2, RetryDelay: "10ms"},
+		{Name: "task2", Commands: []string{"true"}, Timeout: "10s", Retries: 2, RetryOn: RetryOnTimeout},
+		{Name: "task3", Commands: []string{"true"}, Timeout: "10s", Retries: 1, RetryOn: RetryOnError},
+	})
+	assert.NotEqual(t, nil, err)
+
+	assert.Equal(t, StatusSucceeded, status["task1"].Status)
+	assert.Equal(t, 2, status["task1"].Attempts)
+	assert.Equal(t, []int{1, 2, 2}, attempts(status["task1"].Log))
+
+	assert.Equal(t, StatusFailed, status["task2"].Status)
+	assert.Equal(t, 1, status["task2"].Attempts)
+
+	assert.Equal(t, StatusFailed, status["task3"].Status)
+	assert.Equal(t, 2, status["task3"].Attempts)
+	assert.Equal(t, []int{1, 2}, attempts(status["task3"].Log))
+}
+
+func TestTaskerWhen(t *testing.T) {
+	status, err := runTasker(t, TaskerDefaultConfig(), &runnerTest{fail: map[string]bool{"task1": true}}, []Task{
+		{Name: "task1", Commands: []string{"false"}, Timeout: "10s"},
+		{Name: "task2", Commands: []string{"true"}, Timeout: "10s", Depends: []string{"task1"}, When: "failure()"},
+		{Name: "task3", Commands: []string{"true"}, Timeout: "10s", Depends: []string{"task1"},
+			Params: []TaskParam{{Name: "ENV", Value: "prod"}}, When: "always() && params.ENV == 'dev'"},
+		{Name: "task4", Commands: []string{"true"}, Timeout: "10s", Depends: []string{"task1"}},
+		{Name: "task5", Commands: []string{"true"}, Timeout: "10s", Depends: []string{"task2"},
+			When: "tasks.task2.status == 'succeeded'"},
+		{Name: "task6", Commands: []string{"true"}, Timeout: "10s", Depends: []string{"task3"}, When: "failure()"},
+		{Name: "task7", Commands: []string{"true"}, Timeout: "10s", Depends: []string{"task4"}, When: "failure()"},
+		{Name: "task8", Commands: []string{"true"}, Timeout: "10s", Depends: []string{"task1"},
+			Params: []TaskParam{{Name: "ENV", Value: "prod"}}, When: "params.ENV == 'prod'"},
+	})
+	assert.NotEqual(t, nil, err)
+
+	assert.Equal(t, StatusFailed, status["task1"].Status)
+	assert.Equal(t, StatusSucceeded, status["task2"].Status)
+	assert.Equal(t, StatusSkipped, status["task3"].Status)
+	assert.Equal(t, StatusSkipped, status["task4"].Status)
+	assert.Equal(t, StatusSucceeded, status["task5"].Status)
+	// task3 and task4 are skipped after the failure of task1, which failure() counts.
+	assert.Equal(t, StatusSucceeded, status["task6"].Status)
+	assert.Equal(t, StatusSucceeded, status["task7"].Status)
+	// A condition without success(), failure() or always() requires the depends to have succeeded.
+	assert.Equal(t, StatusSkipped, status["task8"].Status)
+}
+
+func TestTaskerOutputs(t *testing.T) {
+	srv := &runnerTest{lines: map[string][]string{
+		"task1": {OutputPrefix + "VERSION::1.0", OutputPrefix + "BUILD::1", OutputPrefix + "UNDECLARED::1"},
+		"task2": {OutputPrefix + "BUILD::2", OutputPrefix + "invalid"},
+	}}
+
+	status, err := runTasker(t, TaskerDefaultConfig(), srv, []Task{
+		{Name: "task1", Commands: []string{"true"}, Timeout: "10s", Outputs: []string{"VERSION", "BUILD"}},
+		{Name: "task2", Commands: []string{"true"}, Timeout: "10s", Outputs: []string{"BUILD"}, Depends: []string{"task1"}},
+		{Name: "task3", Commands: []string{"true"}, Timeout: "10s", Depends: []string{"task2"},
+			Params: []TaskParam{{Name: "VERSION", Value: "0.1"}, {Name: "ENV", Value: "prod"}},
+			When:   "tasks.task2.outputs.BUILD == '2'"},
+	})
+	assert.Equal(t, nil, err)
+
+	assert.Equal(t, map[string]string{"VERSION": "1.0", "BUILD": "1"}, status["task1"].Outputs)
+	assert.Equal(t, map[string]string{"BUILD": "2"}, status["task2"].Outputs)
+	assert.Equal(t, StatusSucceeded, status["task3"].Status)
+
+	assert.Equal(t, map[string]string{"VERSION": "1.0", "BUILD": "2", "ENV": "prod"}, srv.params["task3"])
+}
+
+func TestParseOutput(t *testing.T) {
+	key, val, ok := parseOutput(OutputPrefix + "VERSION::1.0::rc1")
+	assert.Equal(t, true, ok)
+	assert.Equal(t, "VERSION", key)
+	assert.Equal(t, "1.0::rc1", val)
+
+	_, _, ok = parseOutput("VERSION=1.0")
+	assert.Equal(t, false, ok)
+
+	_, _, ok = parseOutput(OutputPrefix + "::1.0")
+	assert.Equal(t, false, ok)
+}
+
+func TestTaskerResume(t *testing.T) {
+	c := TaskerDefaultConfig()
+	c.Resume = []TaskStatus{
+		{Name: "task1", Status: StatusSucceeded, Attempts: 1},
+		{Name: "task2", Status: StatusFailed, Attempts: 1, Error: "exit status 1"},
+	}
+
+	status, err := runTasker(t, c, &runnerTest{fail: map[string]bool{"task1": true}}, []Task{
+		{Name: "task1", Commands: []string{"true"}, Timeout: "10s"},
+		{Name: "task2", Commands: []string{"true"}, Timeout: "10s", Depends: []string{"task1"}},
+	})
+	assert.Equal(t, nil, err)
+
+	assert.Equal(t, StatusSucceeded, status["task1"].Status)
+	assert.Equal(t, true, status["task1"].Resumed)
+
+	assert.Equal(t, StatusSucceeded, status["task2"].Status)
+	assert.Equal(t, false, status["task2"].Resumed)
+	assert.Equal(t, "", status["task2"].Error)
+}
+
+func TestTaskerCancel(t *testing.T) {
+	srv := &runnerTest{block: map[string]bool{"task1": true}, canceled: make(chan string, 1)}
+
+	c := TaskerDefaultConfig()
+	c.Data.Spec.Timeout = "300ms"
+
+	start := time.Now()
+
+	status, err := runTasker(t, c, srv, []Task{
+		{Name: "task1", Commands: []string{"sleep"}, Timeout: "1h", Retries: 3},
+		{Name: "task2", Commands: []string{"true"}, Timeout: "10s", Depends: []string{"task1"}},
+	})
+	assert.NotEqual(t, nil, err)
+	assert.Less(t, time.Since(start), 5*time.Second)
+	assert.Equal(t, "task1", <-srv.canceled)
+
+	assert.Equal(t, StatusCanceled, status["task1"].Status)
+	assert.Equal(t, 1, status["task1"].Attempts)
+	assert.Equal(t, StatusCanceled, status["task2"].Status)
+}
+
+func TestTaskerPlan(t *testing.T) {
+	ctx := context.Background()
+
+	c := TaskerDefaultConfig()
+	c.Dag = dag.New(ctx, dag.DefaultConfig())
+	c.Scheduler = &schedulerTest{res: scheduler.Result{Name: "node1", Host: "127.0.0.2"}}
+	c.Config.Spec.Runner.Port = 29090
+	c.Data.Spec.Tasks = []Task{
+		{Name: "task1", Commands: []string{"true"}, Timeout: "10s", Language: TaskLanguage{Name: "bash"}},
+		{Name: "task2", Commands: []string{"true"}, Timeout: "10s", Depends: []string{"task1"}},
+		{Name: "task3", Commands: []string{"true"}, Timeout: "10s", Params: []TaskParam{{Name: "a", Value: "b"}}},
+	}
+
+	_t := TaskerNew(ctx, c)
+
+	err := _t.Init(ctx)
+	assert.Equal(t, nil, err)
+
+	defer func() {
+		_ = _t.Deinit(ctx)
+	}()
+
+	plan, err := _t.Plan(ctx)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []TaskPlan{
+		{Level: 1, Name: "task1", Host: "127.0.0.2:29090", Language: "bash", Commands: []string{"true"}, Timeout: "10s"},
+		{Level: 1, Name: "task3", Host: "127.0.0.2:29090", Params: []TaskParam{{Name: "a", Value: "b"}},
+			Commands: []string{"true"}, Timeout: "10s"},
+		{Level: 2, Name: "task2", Host: "127.0.0.2:29090", Depends: []string{"task1"}, Commands: []string{"true"},
+			Timeout: "10s"},
+	}, plan)
+
+	c.Scheduler = &schedulerTest{res: scheduler.Result{Error: "no node"}}
+
+	plan, err = _t.Plan(ctx)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "no node", plan[0].Error)
+}
+
+func TestTaskerFilter(t *testing.T) {
+	d := dag.DefaultConfig()
+	d.Filter = dag.Filter{From: []string{"task3"}}
+
+	c := TaskerDefaultConfig()
+	c.Dag = dag.New(context.Background(), d)
+
+	status, err := runTasker(t, c, &runnerTest{fail: map[string]bool{"task1": true}}, []Task{
+		{Name: "task1", Commands: []string{"false"}, Timeout: "10s"},
+		{Name: "task2", Commands: []string{"true"}, Timeout: "10s"},
+		{Name: "task3", Commands: []string{"true"}, Timeout: "10s", Depends: []string{"task1", "task2"}},
+		{Name: "task4", Commands: []string{"true"}, Timeout: "10s", Depends: []string{"task3"}},
+	})
+	assert.Equal(t, nil, err)
+
+	assert.Equal(t, map[string]string{"task3": StatusSucceeded, "task4": StatusSucceeded}, statuses(status))
+
+	d.Filter = dag.Filter{Skip: []string{"task3"}}
+
+	c = TaskerDefaultConfig()
+	c.Dag = dag.New(context.Background(), d)
+
+	status, err = runTasker(t, c, &runnerTest{fail: map[string]bool{"task1": true}}, []Task{
+		{Name: "task1", Commands: []string{"false"}, Timeout: "10s"},
+		{Name: "task2", Commands: []string{"true"}, Timeout: "10s"},
+		{Name: "task3", Commands: []string{"true"}, Timeout: "10s", Depends: []string{"task1", "task2"}},
+		{Name: "task4", Commands: []string{"true"}, Timeout: "10s", Depends: []string{"task3"}},
+	})
+	assert.NotEqual(t, nil, err)
+
+	// task4 depends on task1 and task2 in place of task3, so the failure of task1 skips it.
+	assert.Equal(t, map[string]string{"task1": StatusFailed, "task2": StatusSucceeded, "task4": StatusSkipped}, statuses(status))
+}
+
+func TestRetryable(t *testing.T) {
+	timeout := errors.Wrap(context.DeadlineExceeded, "failed to recv")
+	failure := errors.New("exit status 1")
+
+	assert.Equal(t, true, retryable("", timeout))
+	assert.Equal(t, true, retryable(RetryOnAny, failure))
+	assert.Equal(t, true, retryable(RetryOnTimeout, timeout))
+	assert.Equal(t, false, retryable(RetryOnTimeout, failure))
+	assert.Equal(t, true, retryable(RetryOnError, failure))
+	assert.Equal(t, false, retryable(RetryOnError, timeout))
+	assert.Equal(t, true, retryable(RetryOnTimeout, status.Error(codes.DeadlineExceeded, "deadline")))
+}
+
+func attempts(log []TaskOutput) []int {
+	var buf []int
+	for _, item := range log {
+		buf = append(buf, item.Attempt)
+	}
+	return buf
+}
@@ -0,0 +1,97 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	LeftDelim  = "${{"
+	RightDelim = "}}"
+)
+
+// Render executes the templates in the fields of tasks, e.g. ${{ .version }} or ${{ env "HOME" }},
+// with spec.vars overridden by vars. It fails naming the first invalid field.
+func Render(data *Proto, vars map[string]string) error {
+	values := map[string]string{}
+
+	for key, val := range data.Spec.Vars {
+		values[key] = val
+	}
+
+	for key, val := range vars {
+		values[key] = val
+	}
+
+	for i := range data.Spec.Tasks {
+		if err := render(fmt.Sprintf("spec.tasks[%d]", i), reflect.ValueOf(&data.Spec.Tasks[i]).Elem(), values); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func render(path string, v reflect.Value, values map[string]string) error {
+	switch v.Kind() {
+	case reflect.String:
+		buf, err := execute(v.String(), values)
+		if err != nil {
+			return errors.Wrap(err, path)
+		}
+		v.SetString(buf)
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			name := strings.Split(v.Type().Field(i).Tag.Get("json"), ",")[0]
+			if err := render(path+"."+name, v.Field(i), values); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			if err := render(fmt.Sprintf("%s[%d]", path, i), v.Index(i), values); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			val := reflect.New(iter.Value().Type()).Elem()
+			val.Set(iter.Value())
+			if err := render(path+"."+iter.Key().String(), val, values); err != nil {
+				return err
+			}
+			v.SetMapIndex(iter.Key(), val)
+		}
+	}
+
+	return nil
+}
+
+func execute(text string, values map[string]string) (string, error) {
+	if !strings.Contains(text, LeftDelim) {
+		return text, nil
+	}
+
+	tmpl, err := template.New("").
+		Delims(LeftDelim, RightDelim).
+		Funcs(template.FuncMap{"env": os.Getenv}).
+		Option("missingkey=error").
+		Parse(text)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to parse")
+	}
+
+	var b strings.Builder
+
+	if err := tmpl.Execute(&b, values); err != nil {
+		return "", errors.Wrap(err, "failed to execute")
+	}
+
+	return b.String(), nil
+}
@@ -0,0 +1,49 @@
+package runner
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRender(t *testing.T) {
+	t.Setenv("PIPEGO_TEST_HOME", "/home/pipego")
+
+	data := Proto{
+		Spec: Spec{
+			Vars: map[string]string{"env": "dev", "version": "1.0"},
+			Tasks: []Task{
+				{
+					Name:     "deploy-${{ .env }}",
+					File:     TaskFile{Content: "echo ${{ .version }}"},
+					Params:   []TaskParam{{Name: "HOME", Value: `${{ env "PIPEGO_TEST_HOME" }}`}},
+					Matrix:   TaskMatrix{"OS": {"${{ .env }}-alpine"}},
+					Commands: []string{"echo", "${{ .env }}", "{{.Names}}"},
+					Timeout:  "${{ .timeout }}",
+				},
+			},
+		},
+	}
+
+	err := Render(&data, map[string]string{"env": "prod", "timeout": "10s"})
+	assert.Equal(t, nil, err)
+
+	task := data.Spec.Tasks[0]
+	assert.Equal(t, "deploy-prod", task.Name)
+	assert.Equal(t, "echo 1.0", task.File.Content)
+	assert.Equal(t, "/home/pipego", task.Params[0].Value)
+	assert.Equal(t, []string{"prod-alpine"}, task.Matrix["OS"])
+	assert.Equal(t, []string{"echo", "prod", "{{.Names}}"}, task.Commands)
+	assert.Equal(t, "10s", task.Timeout)
+
+	data.Spec.Tasks = []Task{{Name: "task1", Commands: []string{"echo", "${{ .unknown }}"}}}
+
+	err = Render(&data, nil)
+	assert.NotEqual(t, nil, err)
+	assert.Contains(t, err.Error(), "spec.tasks[0].commands[1]")
+
+	data.Spec.Tasks = []Task{{Name: "task1", Commands: []string{"echo ${{ .env"}}}
+
+	err = Render(&data, nil)
+	assert.NotEqual(t, nil, err)
+}
@@ -0,0 +1,61 @@
+package runner
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/pipego/cli/config"
+)
+
+const (
+	TaskTimeout   = Time * time.Hour
+	GlanceTimeout = time.Minute
+	MaintTimeout  = time.Minute
+	ConfigTimeout = time.Minute
+)
+
+// Resolve parses the durations of data at load, and sets them to the canonical
+// form or the default of each operation if empty, e.g. 90 to 1m30s.
+// It fails naming the first invalid field.
+func Resolve(data *Proto) error {
+	resolve := func(path string, value *string, parse func(string, time.Duration) (time.Duration, error),
+		d time.Duration) error {
+		duration, err := parse(*value, d)
+		if err != nil {
+			return errors.Wrap(err, path)
+		}
+		if duration != 0 {
+			*value = duration.String()
+		}
+		return nil
+	}
+
+	for i := range data.Spec.Tasks {
+		task := &data.Spec.Tasks[i]
+		path := fmt.Sprintf("spec.tasks[%d]", i)
+		if err := resolve(path+".timeout", &task.Timeout, config.ParseTimeout, TaskTimeout); err != nil {
+			return err
+		}
+		if err := resolve(path+".retryDelay", &task.RetryDelay, config.ParseDuration, 0); err != nil {
+			return err
+		}
+	}
+
+	if data.Spec.Timeout != "" {
+		if err := resolve("spec.timeout", &data.Spec.Timeout, config.ParseTimeout, 0); err != nil {
+			return err
+		}
+	}
+
+	if err := resolve("spec.glance.timeout", &data.Spec.Glance.Timeout, config.ParseTimeout, GlanceTimeout); err != nil {
+		return err
+	}
+
+	if err := resolve("spec.maint.timeout", &data.Spec.Maint.Timeout, config.ParseTimeout, MaintTimeout); err != nil {
+		return err
+	}
+
+	return resolve("spec.config.timeout", &data.Spec.Config.Timeout, config.ParseTimeout, ConfigTimeout)
+}
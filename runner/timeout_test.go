@@ -0,0 +1,34 @@
+package runner
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolve(t *testing.T) {
+	data := Proto{
+		Spec: Spec{
+			Tasks: []Task{
+				{Name: "task1"},
+				{Name: "task2", Timeout: "90", RetryDelay: "1.5"},
+			},
+			Maint: Maint{Timeout: "1h30m"},
+		},
+	}
+
+	assert.Equal(t, nil, Resolve(&data))
+	assert.Equal(t, "12h0m0s", data.Spec.Tasks[0].Timeout)
+	assert.Equal(t, "", data.Spec.Tasks[0].RetryDelay)
+	assert.Equal(t, "1m30s", data.Spec.Tasks[1].Timeout)
+	assert.Equal(t, "1.5s", data.Spec.Tasks[1].RetryDelay)
+	assert.Equal(t, "", data.Spec.Timeout)
+	assert.Equal(t, "1m0s", data.Spec.Glance.Timeout)
+	assert.Equal(t, "1h30m0s", data.Spec.Maint.Timeout)
+
+	data.Spec.Tasks[1].Timeout = "10 s"
+	assert.Equal(t, `spec.tasks[1].timeout: invalid duration "10 s"`, Resolve(&data).Error())
+
+	data.Spec.Tasks[1].Timeout = "0"
+	assert.Equal(t, `spec.tasks[1].timeout: zero duration "0"`, Resolve(&data).Error())
+}
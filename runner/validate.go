@@ -0,0 +1,180 @@
+package runner
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/pipego/cli/config"
+	"github.com/pipego/cli/dag"
+)
+
+var (
+	Languages = []string{"bash", "go", "python", "rust"}
+	RetryOns  = []string{RetryOnAny, RetryOnError, RetryOnTimeout}
+
+	outputName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// Validate reports every problem of data, each prefixed with the JSON path of the field.
+func Validate(data *Proto) []error {
+	var errs []error
+
+	invalid := func(path, format string, args ...interface{}) {
+		errs = append(errs, errors.New(path+": "+fmt.Sprintf(format, args...)))
+	}
+
+	duration := func(path, value string, parse func(string, time.Duration) (time.Duration, error)) {
+		if _, err := parse(value, time.Second); err != nil {
+			invalid(path, "%s", err.Error())
+		}
+	}
+
+	names := map[string]int{}
+
+	for i := range data.Spec.Tasks {
+		task := &data.Spec.Tasks[i]
+		path := fmt.Sprintf("spec.tasks[%d]", i)
+		if task.Name == "" {
+			invalid(path+".name", "name is empty")
+		} else if j, ok := names[task.Name]; ok {
+			invalid(path+".name", "duplicate task %q of spec.tasks[%d]", task.Name, j)
+		} else {
+			names[task.Name] = i
+		}
+		if len(task.Commands) == 0 && task.File.Content == "" && task.File.Path == "" {
+			invalid(path+".commands", "commands, file.content and file.path are all empty")
+		}
+		if task.File.Content != "" && task.File.Path != "" {
+			invalid(path+".file", "content and path are both set")
+		}
+		if task.File.Gzip && task.File.Zstd {
+			invalid(path+".file", "gzip and zstd are both set")
+		}
+		if !contains(Languages, task.Language.Name) {
+			invalid(path+".language.name", "unknown language %q", task.Language.Name)
+		}
+		duration(path+".timeout", task.Timeout, config.ParseTimeout)
+		if task.Retries < 0 {
+			invalid(path+".retries", "negative value %d", task.Retries)
+		}
+		duration(path+".retryDelay", task.RetryDelay, config.ParseDuration)
+		if task.RetryOn != "" && !contains(RetryOns, task.RetryOn) {
+			invalid(path+".retryOn", "unknown value %q", task.RetryOn)
+		}
+		for _, key := range sortedKeys(task.Matrix) {
+			if len(task.Matrix[key]) == 0 {
+				invalid(path+".matrix."+key, "no values")
+			}
+		}
+		for j, key := range task.Outputs {
+			if !outputName.MatchString(key) {
+				invalid(fmt.Sprintf("%s.outputs[%d]", path, j), "invalid name %q", key)
+			}
+		}
+	}
+
+	for i := range data.Spec.Tasks {
+		for j, dep := range data.Spec.Tasks[i].Depends {
+			if _, ok := names[dep]; !ok {
+				invalid(fmt.Sprintf("spec.tasks[%d].depends[%d]", i, j), "unknown task %q", dep)
+			}
+		}
+		if data.Spec.Tasks[i].When != "" {
+			validateWhen(fmt.Sprintf("spec.tasks[%d].when", i), &data.Spec.Tasks[i], invalid)
+		}
+	}
+
+	if cycle := detectCycle(data.Spec.Tasks); len(cycle) != 0 {
+		invalid("spec.tasks", "dependency cycle %s", strings.Join(cycle, " -> "))
+	}
+
+	duration("spec.timeout", data.Spec.Timeout, config.ParseTimeout)
+	duration("spec.glance.timeout", data.Spec.Glance.Timeout, config.ParseTimeout)
+	duration("spec.maint.timeout", data.Spec.Maint.Timeout, config.ParseTimeout)
+	duration("spec.config.timeout", data.Spec.Config.Timeout, config.ParseTimeout)
+
+	return errs
+}
+
+// validateWhen checks the condition of task, whose tasks referred must be in depends to be finished before.
+func validateWhen(path string, task *Task, invalid func(string, string, ...interface{})) {
+	w, err := dag.ParseWhen(task.When)
+	if err != nil {
+		invalid(path, "%s", err.Error())
+		return
+	}
+
+	for _, name := range w.Tasks() {
+		if !contains(task.Depends, name) {
+			invalid(path, "task %q not in depends", name)
+		}
+	}
+}
+
+func contains(list []string, name string) bool {
+	for _, item := range list {
+		if name == item {
+			return true
+		}
+	}
+
+	return false
+}
+
+// detectCycle returns the first dependency cycle found in tasks, e.g. [task1 task2 task1].
+func detectCycle(tasks []Task) []string {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	depends := map[string][]string{}
+	for i := range tasks {
+		depends[tasks[i].Name] = tasks[i].Depends
+	}
+
+	state := map[string]int{}
+
+	var stack []string
+	var visit func(string) []string
+
+	visit = func(name string) []string {
+		state[name] = visiting
+		stack = append(stack, name)
+		for _, dep := range depends[name] {
+			if _, ok := depends[dep]; !ok {
+				continue
+			}
+			switch state[dep] {
+			case visiting:
+				for i := range stack {
+					if stack[i] == dep {
+						return append(append([]string{}, stack[i:]...), dep)
+					}
+				}
+			case unvisited:
+				if cycle := visit(dep); len(cycle) != 0 {
+					return cycle
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[name] = visited
+		return nil
+	}
+
+	for i := range tasks {
+		if state[tasks[i].Name] == unvisited {
+			if cycle := visit(tasks[i].Name); len(cycle) != 0 {
+				return cycle
+			}
+		}
+	}
+
+	return nil
+}
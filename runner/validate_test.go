@@ -0,0 +1,59 @@
+package runner
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	data := Proto{
+		Spec: Spec{
+			Tasks: []Task{
+				{Name: "task1", Commands: []string{"echo"}, Language: TaskLanguage{Name: "bash"}, Timeout: "10s"},
+				{Name: "task2", Commands: []string{"echo"}, Language: TaskLanguage{Name: "bash"}, Timeout: "10s",
+					Depends: []string{"task1"}},
+			},
+		},
+	}
+
+	assert.Equal(t, 0, len(Validate(&data)))
+
+	data.Spec.Tasks = append(data.Spec.Tasks,
+		Task{Name: "task1", Commands: []string{"echo"}, Language: TaskLanguage{Name: "bash"}, Timeout: "10s"},
+		Task{Name: "task3", Language: TaskLanguage{Name: "cobol"}, Timeout: "10 s", Depends: []string{"task4", "task5"}},
+		Task{Name: "task4", Commands: []string{"echo"}, Language: TaskLanguage{Name: "bash"}, Timeout: "10s",
+			Depends: []string{"task3"}},
+		Task{Name: "task6", Commands: []string{"echo"}, Language: TaskLanguage{Name: "bash"}, Timeout: "10s",
+			Retries: -1, RetryDelay: "1 s", RetryOn: "never", Outputs: []string{"VERSION", "BUILD-ID"},
+			File: TaskFile{Gzip: true, Zstd: true}, Matrix: TaskMatrix{"GO": {}}},
+		Task{Name: "task7", Commands: []string{"echo"}, Language: TaskLanguage{Name: "bash"}, Timeout: "10s",
+			Depends: []string{"task1"}, When: "failure('task1') || tasks.task6.status == 'failed'"},
+		Task{Name: "task8", Commands: []string{"echo"}, Language: TaskLanguage{Name: "bash"}, Timeout: "10s",
+			When: "failure("},
+	)
+	data.Spec.Glance.Timeout = "ten"
+
+	var errs []string
+	for _, item := range Validate(&data) {
+		errs = append(errs, item.Error())
+	}
+
+	assert.Equal(t, []string{
+		`spec.tasks[2].name: duplicate task "task1" of spec.tasks[0]`,
+		`spec.tasks[3].commands: commands, file.content and file.path are all empty`,
+		`spec.tasks[3].language.name: unknown language "cobol"`,
+		`spec.tasks[3].timeout: invalid duration "10 s"`,
+		`spec.tasks[5].file: gzip and zstd are both set`,
+		`spec.tasks[5].retries: negative value -1`,
+		`spec.tasks[5].retryDelay: invalid duration "1 s"`,
+		`spec.tasks[5].retryOn: unknown value "never"`,
+		`spec.tasks[5].matrix.GO: no values`,
+		`spec.tasks[5].outputs[1]: invalid name "BUILD-ID"`,
+		`spec.tasks[3].depends[1]: unknown task "task5"`,
+		`spec.tasks[6].when: task "task6" not in depends`,
+		`spec.tasks[7].when: expected task name of failure() but got ""`,
+		`spec.tasks: dependency cycle task3 -> task4 -> task3`,
+		`spec.glance.timeout: invalid duration "ten"`,
+	}, errs)
+}
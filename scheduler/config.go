@@ -1,45 +1,46 @@
 package scheduler
 
 type Proto struct {
-	ApiVersion string   `json:"apiVersion"`
-	Kind       string   `json:"kind"`
-	Metadata   Metadata `json:"metadata"`
-	Spec       Spec     `json:"spec"`
+	ApiVersion string   `json:"apiVersion" yaml:"apiVersion"`
+	Kind       string   `json:"kind" yaml:"kind"`
+	Metadata   Metadata `json:"metadata" yaml:"metadata"`
+	Spec       Spec     `json:"spec" yaml:"spec"`
 }
 
 type Metadata struct {
-	Name string `json:"name"`
+	Name string `json:"name" yaml:"name"`
 }
 
 type Spec struct {
-	Task  Task   `json:"task"`
-	Nodes []Node `json:"nodes"`
+	Task  Task   `json:"task" yaml:"task"`
+	Nodes []Node `json:"nodes" yaml:"nodes"`
 }
 
 type Task struct {
-	Name                   string   `json:"name"`
-	NodeName               string   `json:"nodeName"`
-	NodeSelectors          []string `json:"nodeSelectors"`
-	RequestedResource      Resource `json:"requestedResource"`
-	ToleratesUnschedulable bool     `json:"toleratesUnschedulable"`
+	Name                   string   `json:"name" yaml:"name"`
+	NodeName               string   `json:"nodeName" yaml:"nodeName"`
+	NodeSelectors          []string `json:"nodeSelectors" yaml:"nodeSelectors"`
+	RequestedResource      Resource `json:"requestedResource" yaml:"requestedResource"`
+	ToleratesUnschedulable bool     `json:"toleratesUnschedulable" yaml:"toleratesUnschedulable"`
 }
 
 type Node struct {
-	Name                string   `json:"name"`
-	Host                string   `json:"host"`
-	Label               string   `json:"label"`
-	AllocatableResource Resource `json:"allocatableResource"`
-	RequestedResource   Resource `json:"requestedResource"`
-	Unschedulable       bool     `json:"unschedulable"`
+	Name                string   `json:"name" yaml:"name"`
+	Host                string   `json:"host" yaml:"host"`
+	Label               string   `json:"label" yaml:"label"`
+	AllocatableResource Resource `json:"allocatableResource" yaml:"allocatableResource"`
+	RequestedResource   Resource `json:"requestedResource" yaml:"requestedResource"`
+	Unschedulable       bool     `json:"unschedulable" yaml:"unschedulable"`
 }
 
 type Resource struct {
-	MilliCPU int64 `json:"milliCPU"`
-	Memory   int64 `json:"memory"`
-	Storage  int64 `json:"storage"`
+	MilliCPU int64 `json:"milliCPU" yaml:"milliCPU"`
+	Memory   int64 `json:"memory" yaml:"memory"`
+	Storage  int64 `json:"storage" yaml:"storage"`
 }
 
 type Result struct {
-	Name  string `json:"name"`
-	Error string `json:"error"`
+	Name  string `json:"name" yaml:"name"`
+	Host  string `json:"host" yaml:"host"`
+	Error string `json:"error" yaml:"error"`
 }
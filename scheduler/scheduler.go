@@ -2,12 +2,9 @@ package scheduler
 
 import (
 	"context"
-	"math"
-	"strconv"
 
 	"github.com/pkg/errors"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 
 	"github.com/pipego/cli/config"
 	proto "github.com/pipego/cli/scheduler/proto"
@@ -16,7 +13,8 @@ import (
 type Scheduler interface {
 	Init(context.Context) error
 	Deinit(context.Context) error
-	Run(context.Context) (Result, error)
+	Run(context.Context, Task) (Result, error)
+	Task(context.Context) Task
 }
 
 type Config struct {
@@ -40,18 +38,25 @@ func DefaultConfig() *Config {
 	return &Config{}
 }
 
-func (s *scheduler) Init(_ context.Context) error {
-	var err error
+func (s *scheduler) Init(ctx context.Context) error {
+	server := s.cfg.Config.Spec.Scheduler
 
-	host := s.cfg.Config.Spec.Scheduler.Host
-	port := s.cfg.Config.Spec.Scheduler.Port
+	timeout, err := server.DialTimeout()
+	if err != nil {
+		return errors.Wrap(err, "invalid timeout")
+	}
 
-	s.conn, err = grpc.Dial(host+":"+strconv.Itoa(port),
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
-		grpc.WithBlock(),
-		grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(math.MaxInt32), grpc.MaxCallSendMsgSize(math.MaxInt32)))
+	opts, err := server.DialOptions(proto.ServerProto_SendServer_FullMethodName)
 	if err != nil {
-		return errors.Wrap(err, "failed to dial")
+		return errors.Wrap(err, "failed to init options")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	s.conn, err = grpc.DialContext(ctx, server.Address(), append(opts, grpc.WithBlock(), grpc.WithReturnConnectionError())...)
+	if err != nil {
+		return errors.Wrap(err, "failed to dial "+server.Address())
 	}
 
 	s.client = proto.NewServerProtoClient(s.conn)
@@ -63,18 +68,18 @@ func (s *scheduler) Deinit(_ context.Context) error {
 	return s.conn.Close()
 }
 
-func (s *scheduler) Run(ctx context.Context) (Result, error) {
+func (s *scheduler) Run(ctx context.Context, t Task) (Result, error) {
 	task := func() *proto.Task {
 		return &proto.Task{
-			Name:          s.cfg.Data.Spec.Task.Name,
-			NodeName:      s.cfg.Data.Spec.Task.NodeName,
-			NodeSelectors: s.cfg.Data.Spec.Task.NodeSelectors,
+			Name:          t.Name,
+			NodeName:      t.NodeName,
+			NodeSelectors: t.NodeSelectors,
 			RequestedResource: &proto.RequestedResource{
-				MilliCPU: s.cfg.Data.Spec.Task.RequestedResource.MilliCPU,
-				Memory:   s.cfg.Data.Spec.Task.RequestedResource.Memory,
-				Storage:  s.cfg.Data.Spec.Task.RequestedResource.Storage,
+				MilliCPU: t.RequestedResource.MilliCPU,
+				Memory:   t.RequestedResource.Memory,
+				Storage:  t.RequestedResource.Storage,
 			},
-			ToleratesUnschedulable: s.cfg.Data.Spec.Task.ToleratesUnschedulable,
+			ToleratesUnschedulable: t.ToleratesUnschedulable,
 		}
 	}()
 
@@ -117,5 +122,19 @@ func (s *scheduler) Run(ctx context.Context) (Result, error) {
 		return Result{}, errors.Wrap(err, "failed to send")
 	}
 
-	return Result{Name: reply.GetName(), Error: reply.GetError()}, nil
+	return Result{Name: reply.GetName(), Host: s.host(reply.GetName()), Error: reply.GetError()}, nil
+}
+
+func (s *scheduler) Task(_ context.Context) Task {
+	return s.cfg.Data.Spec.Task
+}
+
+func (s *scheduler) host(name string) string {
+	for _, item := range s.cfg.Data.Spec.Nodes {
+		if item.Name == name {
+			return item.Host
+		}
+	}
+
+	return ""
 }
@@ -2,7 +2,9 @@ package scheduler
 
 import (
 	"context"
+	"net"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -11,3 +13,23 @@ func TestScheduler(t *testing.T) {
 	s := New(context.Background(), DefaultConfig())
 	assert.NotEqual(t, nil, s)
 }
+
+func TestSchedulerUnreachable(t *testing.T) {
+	ctx := context.Background()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Equal(t, nil, err)
+	_ = lis.Close()
+
+	c := DefaultConfig()
+	c.Config.Spec.Scheduler.Host = "127.0.0.1"
+	c.Config.Spec.Scheduler.Port = lis.Addr().(*net.TCPAddr).Port
+	c.Config.Spec.Scheduler.Timeout = "200ms"
+
+	start := time.Now()
+
+	err = New(ctx, c).Init(ctx)
+	assert.NotEqual(t, nil, err)
+	assert.Contains(t, err.Error(), lis.Addr().String())
+	assert.Less(t, time.Since(start), 2*time.Second)
+}
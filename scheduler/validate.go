@@ -0,0 +1,61 @@
+package scheduler
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// Validate reports every problem of data, each prefixed with the JSON path of the field.
+func Validate(data *Proto) []error {
+	var errs []error
+
+	invalid := func(path, format string, args ...interface{}) {
+		errs = append(errs, errors.New(path+": "+fmt.Sprintf(format, args...)))
+	}
+
+	resource := func(path string, r Resource) {
+		if r.MilliCPU < 0 {
+			invalid(path+".milliCPU", "negative value %d", r.MilliCPU)
+		}
+		if r.Memory < 0 {
+			invalid(path+".memory", "negative value %d", r.Memory)
+		}
+		if r.Storage < 0 {
+			invalid(path+".storage", "negative value %d", r.Storage)
+		}
+	}
+
+	if len(data.Spec.Nodes) == 0 {
+		invalid("spec.nodes", "nodes are empty")
+	}
+
+	names := map[string]int{}
+
+	for i := range data.Spec.Nodes {
+		node := &data.Spec.Nodes[i]
+		path := fmt.Sprintf("spec.nodes[%d]", i)
+		if node.Name == "" {
+			invalid(path+".name", "name is empty")
+		} else if j, ok := names[node.Name]; ok {
+			invalid(path+".name", "duplicate node %q of spec.nodes[%d]", node.Name, j)
+		} else {
+			names[node.Name] = i
+		}
+		if node.Host == "" {
+			invalid(path+".host", "host is empty")
+		}
+		resource(path+".allocatableResource", node.AllocatableResource)
+		resource(path+".requestedResource", node.RequestedResource)
+	}
+
+	if name := data.Spec.Task.NodeName; name != "" {
+		if _, ok := names[name]; !ok {
+			invalid("spec.task.nodeName", "unknown node %q", name)
+		}
+	}
+
+	resource("spec.task.requestedResource", data.Spec.Task.RequestedResource)
+
+	return errs
+}
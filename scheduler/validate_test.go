@@ -0,0 +1,41 @@
+package scheduler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	data := Proto{
+		Spec: Spec{
+			Task: Task{NodeName: "node1"},
+			Nodes: []Node{
+				{Name: "node1", Host: "127.0.0.1"},
+			},
+		},
+	}
+
+	assert.Equal(t, 0, len(Validate(&data)))
+
+	data.Spec.Task.NodeName = "node3"
+	data.Spec.Nodes = append(data.Spec.Nodes,
+		Node{Name: "node1", Host: "127.0.0.1"},
+		Node{Name: "node2", AllocatableResource: Resource{Memory: -1}},
+	)
+
+	var errs []string
+	for _, item := range Validate(&data) {
+		errs = append(errs, item.Error())
+	}
+
+	assert.Equal(t, []string{
+		`spec.nodes[1].name: duplicate node "node1" of spec.nodes[0]`,
+		`spec.nodes[2].host: host is empty`,
+		`spec.nodes[2].allocatableResource.memory: negative value -1`,
+		`spec.task.nodeName: unknown node "node3"`,
+	}, errs)
+
+	data.Spec.Nodes = nil
+	assert.Equal(t, 2, len(Validate(&data)))
+}
@@ -0,0 +1,119 @@
+package state
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/pipego/cli/runner"
+)
+
+const (
+	Dir = ".pipego/state"
+
+	filePerm = 0o600
+	dirPerm  = 0o750
+)
+
+// State is the status of tasks of one run of the pipeline name.
+type State struct {
+	Name    string              `json:"name"`
+	RunID   string              `json:"runId"`
+	Updated time.Time           `json:"updated"`
+	Tasks   []runner.TaskStatus `json:"tasks"`
+}
+
+// Store keeps states in dir as <name>/<run id>.json.
+type Store struct {
+	dir string
+}
+
+func New(dir string) *Store {
+	if dir == "" {
+		dir = Dir
+	}
+
+	return &Store{dir: dir}
+}
+
+// NewRunID returns a run id sortable by time, e.g. 20261018-104427-1a2b3c.
+func NewRunID() string {
+	b := make([]byte, 3)
+	_, _ = rand.Read(b)
+
+	return time.Now().Format("20060102-150405") + "-" + hex.EncodeToString(b)
+}
+
+// Merge sets the status of tasks in st by name, keeping the tasks not run, e.g. filtered out.
+func (st *State) Merge(tasks []runner.TaskStatus) {
+	index := map[string]int{}
+	for i := range st.Tasks {
+		index[st.Tasks[i].Name] = i
+	}
+
+	for i := range tasks {
+		if j, ok := index[tasks[i].Name]; ok {
+			st.Tasks[j] = tasks[i]
+		} else {
+			index[tasks[i].Name] = len(st.Tasks)
+			st.Tasks = append(st.Tasks, tasks[i])
+		}
+	}
+}
+
+func (s *Store) Load(name, runID string) (*State, error) {
+	buf, err := os.ReadFile(s.path(name, runID))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read")
+	}
+
+	var st State
+
+	if err := json.Unmarshal(buf, &st); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal")
+	}
+
+	return &st, nil
+}
+
+// Save writes st to a temporary file renamed then, so that a state is never left half written.
+func (s *Store) Save(st *State) error {
+	name := s.path(st.Name, st.RunID)
+
+	if err := os.MkdirAll(filepath.Dir(name), dirPerm); err != nil {
+		return errors.Wrap(err, "failed to make dir")
+	}
+
+	st.Updated = time.Now()
+
+	buf, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal")
+	}
+
+	if err := os.WriteFile(name+".tmp", buf, filePerm); err != nil {
+		return errors.Wrap(err, "failed to write")
+	}
+
+	if err := os.Rename(name+".tmp", name); err != nil {
+		return errors.Wrap(err, "failed to rename")
+	}
+
+	return nil
+}
+
+func (s *Store) path(name, runID string) string {
+	clean := strings.NewReplacer("/", "_", "\\", "_", "..", "_")
+
+	if name == "" {
+		name = "default"
+	}
+
+	return filepath.Join(s.dir, clean.Replace(name), clean.Replace(runID)+".json")
+}
@@ -0,0 +1,55 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pipego/cli/runner"
+)
+
+func TestStore(t *testing.T) {
+	dir := t.TempDir()
+	s := New(dir)
+
+	runID := NewRunID()
+	assert.NotEqual(t, runID, NewRunID())
+
+	_, err := s.Load("pipeline", runID)
+	assert.NotEqual(t, nil, err)
+
+	st := &State{
+		Name:  "pipeline",
+		RunID: runID,
+		Tasks: []runner.TaskStatus{
+			{Name: "task1", Status: runner.StatusSucceeded, Log: []runner.TaskOutput{{Pos: 1, Message: "hello"}}},
+			{Name: "task2", Status: runner.StatusFailed, Error: "exit status 1"},
+		},
+	}
+
+	err = s.Save(st)
+	assert.Equal(t, nil, err)
+
+	_, err = os.Stat(filepath.Join(dir, "pipeline", runID+".json"))
+	assert.Equal(t, nil, err)
+
+	_st, err := s.Load("pipeline", runID)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, st.Tasks, _st.Tasks)
+	assert.Equal(t, runID, _st.RunID)
+
+	st.Merge([]runner.TaskStatus{
+		{Name: "task2", Status: runner.StatusSucceeded},
+		{Name: "task3", Status: runner.StatusSucceeded},
+	})
+	assert.Equal(t, []runner.TaskStatus{
+		_st.Tasks[0],
+		{Name: "task2", Status: runner.StatusSucceeded},
+		{Name: "task3", Status: runner.StatusSucceeded},
+	}, st.Tasks)
+
+	assert.Equal(t, filepath.Join(dir, "a_b", "_.json"), s.path("a/b", ".."))
+	assert.Equal(t, filepath.Join(Dir, "default", "1.json"), New("").path("", "1"))
+}